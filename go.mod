@@ -1,14 +1,16 @@
 module github.com/outdead/goservice
 
-go 1.15
+go 1.16
 
 require (
+	github.com/BurntSushi/toml v0.3.1
 	github.com/ClickHouse/clickhouse-go v1.4.3
 	github.com/alecthomas/template v0.0.0-20190718012654-fb15b899a751
 	github.com/assembla/cony v0.3.2
 	github.com/go-pg/pg/v9 v9.2.0
 	github.com/go-redis/redis/v8 v8.7.1
 	github.com/jmoiron/sqlx v1.3.1
+	github.com/joho/godotenv v1.3.0
 	github.com/labstack/echo/v4 v4.2.1
 	github.com/olivere/elastic v6.2.35+incompatible
 	github.com/pkg/errors v0.9.1 // indirect
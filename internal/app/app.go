@@ -1,30 +1,59 @@
 package app
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
 	"github.com/outdead/goservice/internal/app/daemon"
+	"github.com/outdead/goservice/internal/utils/configutil"
 	"github.com/outdead/goservice/internal/utils/logutil"
 	"github.com/urfave/cli/v2"
 )
 
+// ErrEmptyConfig is returned when command requires config files but they are
+// not set.
+var ErrEmptyConfig = errors.New("config file is not set, use --config flag")
+
 // App is main application.
 type App struct {
-	name    string
-	version string
-	logger  *logutil.Logger
-	cli     *cli.App
+	name      string
+	version   string
+	commit    string
+	buildDate string
+	logger    *logutil.Logger
+	cli       *cli.App
+}
+
+// Option allows to inject options to App.
+type Option func(a *App)
+
+// SetCommit sets VCS revision the App is built from.
+func SetCommit(commit string) Option {
+	return func(a *App) {
+		a.commit = commit
+	}
+}
+
+// SetBuildDate sets date when the App is built.
+func SetBuildDate(date string) Option {
+	return func(a *App) {
+		a.buildDate = date
+	}
 }
 
 // New creates and returns new App.
-func New(name, version string) *App {
+func New(name, version string, options ...Option) *App {
 	app := App{
 		name:    name,
 		version: version,
 		logger:  logutil.New(logutil.SetService(name), logutil.SetVersion(version)),
 	}
 
+	for _, option := range options {
+		option(&app)
+	}
+
 	return &app
 }
 
@@ -42,35 +71,50 @@ func (a *App) init() {
 	app.Name = a.name
 	app.Version = a.version
 	app.Flags = []cli.Flag{
-		&cli.StringFlag{
-			Name:     "config",
-			Aliases:  []string{"c"},
-			Usage:    "Path to config file",
-			Required: true,
-		},
+		configFlag(),
 		&cli.BoolFlag{
 			Name:    "print",
 			Aliases: []string{"p"},
-			Usage:   "Print config file and exit",
+			Usage:   "Print config file and exit (deprecated, use config print command)",
 		},
 	}
 
-	app.Action = a.action()
+	// Running without command is kept for backward compatibility and is the
+	// same as the serve command.
+	app.Action = a.serveAction()
+	app.Commands = []*cli.Command{
+		a.serveCommand(),
+		a.checkCommand(),
+		a.healthcheckCommand(),
+		a.migrateCommand(),
+		a.versionCommand(),
+		a.configCommand(),
+	}
 
 	a.cli = app
 }
 
-func (a *App) action() func(c *cli.Context) error {
+// serveCommand returns command which runs the daemon.
+func (a *App) serveCommand() *cli.Command {
+	return &cli.Command{
+		Name:   "serve",
+		Usage:  "Run the service",
+		Flags:  []cli.Flag{configFlag()},
+		Action: a.serveAction(),
+	}
+}
+
+func (a *App) serveAction() func(c *cli.Context) error {
 	return func(c *cli.Context) error {
-		cfg, err := daemon.NewConfig(c.String("config"))
+		cfg, err := a.loadConfig(c)
 		if err != nil {
-			return fmt.Errorf("new config: %w", err)
+			return err
 		}
 
 		if c.Bool("print") {
 			a.logger.NewEntry().Info("got -p flag - print config and terminate")
 
-			return cfg.Print(os.Stdout)
+			return cfg.Print(c.App.Writer, c.App.ErrWriter)
 		}
 
 		if err := cfg.Validate(); err != nil {
@@ -90,3 +134,52 @@ func (a *App) action() func(c *cli.Context) error {
 		return d.Run()
 	}
 }
+
+// configFlag returns --config flag. It is registered globally and on the
+// commands which load config, so it can be passed before and after the
+// command name.
+func configFlag() cli.Flag {
+	return &cli.StringSliceFlag{
+		Name:    "config",
+		Aliases: []string{"c"},
+		Usage:   "Path to config file (.yaml, .yml, .json, .toml or .env), can be repeated to merge several files in order",
+	}
+}
+
+// configFiles returns files of --config flag. The flag of the command takes
+// precedence over the global one.
+func configFiles(c *cli.Context) []string {
+	for _, ctx := range c.Lineage() {
+		if names := ctx.StringSlice("config"); len(names) != 0 {
+			return names
+		}
+	}
+
+	return nil
+}
+
+// loadConfig reads config files from --config flag, applies environment
+// variables, resolves secrets and sets defaults. Config is not validated.
+func (a *App) loadConfig(c *cli.Context) (*daemon.Config, error) {
+	names := configFiles(c)
+	if len(names) == 0 {
+		return nil, ErrEmptyConfig
+	}
+
+	cfg, err := daemon.NewConfig(names...)
+	if err != nil {
+		return nil, fmt.Errorf("new config: %w", err)
+	}
+
+	if err := cfg.ParseFromEnv(configutil.EnvName(a.name)); err != nil {
+		return nil, fmt.Errorf("new config: %w", err)
+	}
+
+	if err := cfg.ResolveSecrets(); err != nil {
+		return nil, fmt.Errorf("new config: %w", err)
+	}
+
+	cfg.SetDefaults()
+
+	return cfg, nil
+}
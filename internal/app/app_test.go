@@ -0,0 +1,47 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli/v2"
+)
+
+func TestConfigFiles(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{"global flag", []string{"app", "-c", "a.yaml", "config", "print"}, []string{"a.yaml"}},
+		{"command flag", []string{"app", "serve", "-c", "a.yaml", "-c", "b.yaml"}, []string{"a.yaml", "b.yaml"}},
+		{"subcommand flag", []string{"app", "config", "print", "--config", "a.yaml"}, []string{"a.yaml"}},
+		{"command flag precedence", []string{"app", "-c", "a.yaml", "serve", "-c", "b.yaml"}, []string{"b.yaml"}},
+		{"not set", []string{"app", "serve"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+
+			action := func(c *cli.Context) error {
+				got = configFiles(c)
+
+				return nil
+			}
+
+			app := cli.NewApp()
+			app.Flags = []cli.Flag{configFlag()}
+			app.Commands = []*cli.Command{
+				{Name: "serve", Flags: []cli.Flag{configFlag()}, Action: action},
+				{Name: "config", Subcommands: []*cli.Command{
+					{Name: "print", Flags: []cli.Flag{configFlag()}, Action: action},
+				}},
+			}
+
+			if assert.NoError(t, app.Run(tt.args)) {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package app
+
+import (
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/outdead/goservice/internal/connector"
+	"github.com/urfave/cli/v2"
+)
+
+// checkCommand returns command which checks availability of the configured
+// dependencies.
+func (a *App) checkCommand() *cli.Command {
+	return &cli.Command{
+		Name:   "check",
+		Usage:  "Connect to every configured dependency, report status and latency",
+		Flags:  []cli.Flag{configFlag()},
+		Action: a.checkAction(),
+	}
+}
+
+// checkAction prints status of each configured dependency and exits with
+// non-zero code if any of them is unavailable.
+func (a *App) checkAction() func(c *cli.Context) error {
+	return func(c *cli.Context) error {
+		cfg, err := a.loadConfig(c)
+		if err != nil {
+			return err
+		}
+
+		if err := cfg.Validate(); err != nil {
+			return fmt.Errorf("validate config: %w", err)
+		}
+
+		statuses := connector.Check(&cfg.Connections, connector.SetLogger(a.logger.NewEntry()))
+		failed := 0
+
+		w := tabwriter.NewWriter(c.App.Writer, 0, 0, 2, ' ', 0)
+
+		for _, status := range statuses {
+			latency := status.Latency.Round(time.Millisecond)
+
+			if status.Err != nil {
+				failed++
+
+				fmt.Fprintf(w, "%s\tfail\t%s\t%s\n", status.Name, latency, status.Err)
+
+				continue
+			}
+
+			fmt.Fprintf(w, "%s\tok\t%s\t\n", status.Name, latency)
+		}
+
+		if err := w.Flush(); err != nil {
+			return fmt.Errorf("print statuses: %w", err)
+		}
+
+		if failed != 0 {
+			return cli.Exit(fmt.Sprintf("check failed: %d of %d dependencies are unavailable", failed, len(statuses)), 1)
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,120 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/outdead/goservice/internal/app/daemon"
+	"github.com/outdead/goservice/internal/utils/configutil"
+	"github.com/outdead/goservice/internal/utils/multierror"
+	"github.com/urfave/cli/v2"
+)
+
+// ErrInvalidSchemaFormat is returned when schema is requested for unsupported
+// config format.
+var ErrInvalidSchemaFormat = errors.New("schema format must be yaml or json")
+
+// configCommand returns command with config files tools.
+func (a *App) configCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "config",
+		Usage: "Config files tools",
+		Subcommands: []*cli.Command{
+			{
+				Name:   "print",
+				Usage:  "Print merged config with environment overrides and redacted secrets",
+				Flags:  []cli.Flag{configFlag()},
+				Action: a.configPrintAction(),
+			},
+			{
+				Name:      "validate",
+				Usage:     "Validate config files offline without connecting to anything",
+				ArgsUsage: "[files...]",
+				Flags:     []cli.Flag{configFlag()},
+				Action:    a.configValidateAction(),
+			},
+			{
+				Name:  "schema",
+				Usage: "Print JSON Schema of the config file",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "format",
+						Usage: "Config file format: yaml or json",
+						Value: "yaml",
+					},
+				},
+				Action: a.configSchemaAction(),
+			},
+		},
+	}
+}
+
+// configPrintAction prints config from --config flag files.
+func (a *App) configPrintAction() func(c *cli.Context) error {
+	return func(c *cli.Context) error {
+		cfg, err := a.loadConfig(c)
+		if err != nil {
+			return err
+		}
+
+		return cfg.Print(c.App.Writer, c.App.ErrWriter)
+	}
+}
+
+// configValidateAction validates files from arguments or from --config flag.
+// Each problem is printed on a separate line.
+func (a *App) configValidateAction() func(c *cli.Context) error {
+	return func(c *cli.Context) error {
+		names := c.Args().Slice()
+		if len(names) == 0 {
+			names = configFiles(c)
+		}
+
+		if len(names) == 0 {
+			return ErrEmptyConfig
+		}
+
+		err := daemon.ValidateFiles(configutil.EnvName(a.name), names...)
+		if err == nil {
+			fmt.Fprintln(c.App.Writer, "config is valid")
+
+			return nil
+		}
+
+		errs := []error{err}
+
+		var merr multierror.Error
+		if errors.As(err, &merr) {
+			errs = merr.Errors()
+		}
+
+		for _, e := range errs {
+			fmt.Fprintln(c.App.ErrWriter, e)
+		}
+
+		return cli.Exit(fmt.Sprintf("config is invalid: %d problem(s) found", len(errs)), 1)
+	}
+}
+
+// configSchemaAction prints JSON Schema of the config file.
+func (a *App) configSchemaAction() func(c *cli.Context) error {
+	return func(c *cli.Context) error {
+		format := c.String("format")
+		if format != "yaml" && format != "json" {
+			return fmt.Errorf("%w: %s", ErrInvalidSchemaFormat, format)
+		}
+
+		schema := daemon.Schema(format)
+		schema.Title = a.name + " config"
+
+		js, err := json.MarshalIndent(schema, "", "  ")
+		if err != nil {
+			return fmt.Errorf("marshal schema: %w", err)
+		}
+
+		fmt.Fprintln(c.App.Writer, string(js))
+
+		return nil
+	}
+}
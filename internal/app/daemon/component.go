@@ -0,0 +1,279 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/outdead/goservice/internal/utils/multierror"
+)
+
+// DefaultShutdownTimeout is the time given to each component to stop if
+// app.shutdown.timeout is not set.
+const DefaultShutdownTimeout = 10 * time.Second
+
+// ErrStopTimeout is returned when a component did not stop or drain in the
+// time given.
+var ErrStopTimeout = errors.New("component timeout exceeded")
+
+// Component describes a background routine controlled by the Daemon, e.g.
+// HTTP server, queue consumer or ticker process.
+type Component interface {
+	// Name returns component name. It is used in logs and error messages.
+	Name() string
+
+	// Start runs the component. Start must not block, long running work should
+	// be done in separate goroutines.
+	Start() error
+
+	// Stop stops the component and waits for its goroutines to finish.
+	Stop() error
+
+	// Errors returns component errors channel. The Daemon reads it while the
+	// component is running.
+	Errors() <-chan error
+}
+
+// Drainer is implemented by components which can stop taking new work before
+// they are stopped, e.g. queue consumers stop receiving new messages.
+type Drainer interface {
+	// Drain stops taking new work and waits for in-flight work to finish.
+	Drain() error
+}
+
+// ComponentError is an error received from the Component errors channel.
+type ComponentError struct {
+	Component string
+	Err       error
+}
+
+// Error implements error interface.
+func (e *ComponentError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Component, e.Err)
+}
+
+// Unwrap returns the original component error.
+func (e *ComponentError) Unwrap() error {
+	return e.Err
+}
+
+// component wraps functions to the Component interface.
+type component struct {
+	name   string
+	start  func() error
+	stop   func() error
+	errors <-chan error
+}
+
+// NewComponent creates Component from start and stop functions. It allows you
+// to register routines which do not implement Component interface, e.g.
+//
+//	s := grpc.NewServer()
+//	d.Register(daemon.NewComponent("grpc", func() error {
+//		go func() { _ = s.Serve(lis) }()
+//
+//		return nil
+//	}, func() error {
+//		s.GracefulStop()
+//
+//		return nil
+//	}, nil))
+//
+// Components created by NewComponent are not drained, implement Drainer to
+// stop taking new work before the shutdown, see tickerprocess.Process.
+func NewComponent(name string, start, stop func() error, errs <-chan error) Component {
+	return &component{name: name, start: start, stop: stop, errors: errs}
+}
+
+// Name returns component name.
+func (c *component) Name() string {
+	return c.name
+}
+
+// Start runs the component.
+func (c *component) Start() error {
+	if c.start == nil {
+		return nil
+	}
+
+	return c.start()
+}
+
+// Stop stops the component.
+func (c *component) Stop() error {
+	if c.stop == nil {
+		return nil
+	}
+
+	return c.stop()
+}
+
+// Errors returns component errors channel.
+func (c *component) Errors() <-chan error {
+	return c.errors
+}
+
+// Register adds components to the Daemon. Components are started in order of
+// registration after the built-in HTTP server and profiler and are stopped in
+// reverse order. Register must be called before Run.
+func (d *Daemon) Register(components ...Component) {
+	d.components = append(d.components, components...)
+}
+
+// startComponents starts registered components in order. If one of them fails
+// to start, already started components are stopped.
+func (d *Daemon) startComponents() error {
+	for _, c := range d.components {
+		d.logger.Debugf("starting %s...", c.Name())
+
+		if err := c.Start(); err != nil {
+			if err := d.stopComponents(); err != nil {
+				d.logger.Errorf("stop components error: %s", err)
+			}
+
+			return fmt.Errorf("start %s: %w", c.Name(), err)
+		}
+
+		d.started = append(d.started, d.watchComponent(c))
+	}
+
+	return nil
+}
+
+// stopComponents stops started components in reverse order. Each component
+// is given app.shutdown.timeout to stop.
+func (d *Daemon) stopComponents() error {
+	errs := multierror.New()
+
+	for i := len(d.started) - 1; i >= 0; i-- {
+		rc := d.started[i]
+
+		d.logger.Debugf("stopping %s...", rc.Name())
+
+		rc.unwatch()
+
+		if err := d.stopComponent(rc.Component); err != nil {
+			errs.Append(fmt.Errorf("stop %s: %w", rc.Name(), err))
+		}
+	}
+
+	d.started = nil
+
+	if errs.Len() != 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// drainComponents drains started components which implement Drainer in
+// reverse order. Each component is given app.shutdown.drain_timeout.
+func (d *Daemon) drainComponents() error {
+	errs := multierror.New()
+
+	timeout := d.config.App.Shutdown.DrainTimeout
+	if timeout == 0 {
+		timeout = DefaultShutdownTimeout
+	}
+
+	for i := len(d.started) - 1; i >= 0; i-- {
+		drainer, ok := d.started[i].Component.(Drainer)
+		if !ok {
+			continue
+		}
+
+		d.logger.Debugf("draining %s...", d.started[i].Name())
+
+		if err := callWithTimeout(drainer.Drain, timeout); err != nil {
+			errs.Append(fmt.Errorf("drain %s: %w", d.started[i].Name(), err))
+		}
+	}
+
+	if errs.Len() != 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// stopComponent stops the component and waits for it no longer than
+// app.shutdown.timeout.
+func (d *Daemon) stopComponent(c Component) error {
+	timeout := d.config.App.Shutdown.Timeout
+	if timeout == 0 {
+		timeout = DefaultShutdownTimeout
+	}
+
+	return callWithTimeout(c.Stop, timeout)
+}
+
+// callWithTimeout calls fn and waits for its result no longer than timeout.
+func callWithTimeout(fn func() error, timeout time.Duration) error {
+	done := make(chan error, 1)
+
+	go func() {
+		done <- fn()
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		return fmt.Errorf("%w: %s", ErrStopTimeout, timeout)
+	}
+}
+
+// runningComponent is a started Component whose errors are forwarded to the
+// Daemon.
+type runningComponent struct {
+	Component
+	quit chan struct{}
+	done chan struct{}
+}
+
+// watchComponent starts forwarding errors from the component errors channel
+// to the Daemon.
+func (d *Daemon) watchComponent(c Component) *runningComponent {
+	rc := runningComponent{
+		Component: c,
+		quit:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+
+	go func() {
+		defer close(rc.done)
+
+		for {
+			select {
+			case err, ok := <-rc.Errors():
+				if !ok {
+					// Component closed its errors channel, nothing to watch.
+					return
+				}
+
+				if err == nil {
+					continue
+				}
+
+				select {
+				case d.componentErrors <- &ComponentError{Component: rc.Name(), Err: err}:
+				case <-rc.quit:
+					return
+				}
+			case <-rc.quit:
+				return
+			}
+		}
+	}()
+
+	return &rc
+}
+
+// unwatch stops forwarding of the component errors.
+func (rc *runningComponent) unwatch() {
+	close(rc.quit)
+	<-rc.done
+}
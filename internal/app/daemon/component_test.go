@@ -0,0 +1,173 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/outdead/goservice/internal/utils/logutil"
+	"github.com/outdead/goservice/internal/utils/multierror"
+	"github.com/stretchr/testify/assert"
+)
+
+var errStart = errors.New("start error")
+
+// events records calls of the test components in order.
+type events struct {
+	mu   sync.Mutex
+	list []string
+}
+
+func (e *events) add(event string) {
+	e.mu.Lock()
+	e.list = append(e.list, event)
+	e.mu.Unlock()
+}
+
+func (e *events) get() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return append([]string(nil), e.list...)
+}
+
+// testComponent records its starts and stops.
+type testComponent struct {
+	name      string
+	events    *events
+	startErr  error
+	stopDelay time.Duration
+	errors    chan error
+}
+
+func (c *testComponent) Name() string { return c.name }
+
+func (c *testComponent) Start() error {
+	c.events.add("start " + c.name)
+
+	return c.startErr
+}
+
+func (c *testComponent) Stop() error {
+	time.Sleep(c.stopDelay)
+	c.events.add("stop " + c.name)
+
+	return nil
+}
+
+func (c *testComponent) Errors() <-chan error { return c.errors }
+
+// newTestDaemon creates the Daemon with valid defaults which is not started.
+func newTestDaemon(t *testing.T) *Daemon {
+	t.Helper()
+
+	cfg := new(Config)
+	cfg.App.Port = "8080"
+	cfg.App.CheckConnectionsInterval = time.Minute
+	cfg.App.ErrorBuffer = 10
+	cfg.SetDefaults()
+
+	d := NewDaemon(cfg, logutil.NewDiscardLogger().NewEntry())
+	d.retries = make(chan string, 1)
+	d.restarts = make(chan string, 10)
+
+	t.Cleanup(d.cancel)
+
+	return d
+}
+
+func TestDaemon_Components_Order(t *testing.T) {
+	d := newTestDaemon(t)
+	e := new(events)
+
+	d.Register(
+		&testComponent{name: "a", events: e},
+		&testComponent{name: "b", events: e},
+		&testComponent{name: "c", events: e},
+	)
+
+	if !assert.NoError(t, d.startComponents()) {
+		return
+	}
+
+	assert.NoError(t, d.stopComponents())
+	assert.Equal(t, []string{"start a", "start b", "start c", "stop c", "stop b", "stop a"}, e.get())
+}
+
+func TestDaemon_Components_StartError(t *testing.T) {
+	d := newTestDaemon(t)
+	e := new(events)
+
+	d.Register(
+		&testComponent{name: "a", events: e},
+		&testComponent{name: "b", events: e, startErr: errStart},
+		&testComponent{name: "c", events: e},
+	)
+
+	err := d.startComponents()
+	assert.True(t, errors.Is(err, errStart), "expected %v, got %v", errStart, err)
+
+	// Components started before the failed one are stopped, the next ones
+	// are not started.
+	assert.Equal(t, []string{"start a", "start b", "stop a"}, e.get())
+	assert.Empty(t, d.started)
+}
+
+func TestDaemon_Components_StopTimeout(t *testing.T) {
+	d := newTestDaemon(t)
+	d.config.App.Shutdown.Timeout = 20 * time.Millisecond
+
+	e := new(events)
+
+	d.Register(
+		&testComponent{name: "fast", events: e},
+		&testComponent{name: "slow", events: e, stopDelay: time.Second},
+	)
+
+	if !assert.NoError(t, d.startComponents()) {
+		return
+	}
+
+	start := time.Now()
+	err := d.stopComponents()
+
+	// Each component is given its own timeout, the slow one does not block
+	// stop of the next ones.
+	assert.Less(t, int64(time.Since(start)), int64(500*time.Millisecond))
+	assert.Contains(t, e.get(), "stop fast")
+
+	var merr multierror.Error
+	if assert.True(t, errors.As(err, &merr)) && assert.Equal(t, 1, merr.Len()) {
+		assert.True(t, errors.Is(merr.Errors()[0], ErrStopTimeout), "unexpected error: %v", merr.Errors()[0])
+		assert.Contains(t, merr.Errors()[0].Error(), "stop slow")
+	}
+}
+
+func TestDaemon_Components_Errors(t *testing.T) {
+	d := newTestDaemon(t)
+	c := &testComponent{name: "consumer", events: new(events), errors: make(chan error, 1)}
+
+	d.Register(c)
+
+	if !assert.NoError(t, d.startComponents()) {
+		return
+	}
+
+	defer func() { _ = d.stopComponents() }()
+
+	c.errors <- errStart
+
+	// Component errors are forwarded to the Daemon with the component name.
+	select {
+	case err := <-d.componentErrors:
+		var cerr *ComponentError
+		if assert.True(t, errors.As(err, &cerr)) {
+			assert.Equal(t, "consumer", cerr.Component)
+			assert.Equal(t, fmt.Sprintf("consumer: %s", errStart), err.Error())
+		}
+	case <-time.After(time.Second):
+		t.Error("component error is not forwarded")
+	}
+}
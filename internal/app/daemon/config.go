@@ -6,19 +6,37 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"os"
 	"path"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/outdead/goservice/internal/app/server/http"
 	"github.com/outdead/goservice/internal/connector"
+	"github.com/outdead/goservice/internal/utils/backoff"
+	"github.com/outdead/goservice/internal/utils/configutil"
+	"github.com/outdead/goservice/internal/utils/leader"
 	"github.com/outdead/goservice/internal/utils/logutil"
-	"gopkg.in/yaml.v3"
+	"github.com/outdead/goservice/internal/utils/multierror"
 )
 
 // Validation errors.
 var (
-	ErrEmptyPort                     = errors.New("app.port is empty")
-	ErrEmptyCheckConnectionsInterval = errors.New("app.check_connections_interval is empty")
-	ErrEmptyErrorBuffer              = errors.New("app.error_buffer is empty")
+	ErrEmptyPort                      = errors.New("app.port is empty")
+	ErrEmptyCheckConnectionsInterval  = errors.New("app.check_connections_interval is empty")
+	ErrInvalidCheckConnectionsTimeout = errors.New("app.check_connections_timeout must be positive number")
+	ErrEmptyErrorBuffer               = errors.New("app.error_buffer is empty")
+	ErrInvalidShutdownTimeout         = errors.New("app.shutdown.timeout must be positive number or zero")
+	ErrInvalidDrainDelay              = errors.New("app.shutdown.drain_delay must be positive number or zero")
+	ErrInvalidDrainTimeout            = errors.New("app.shutdown.drain_timeout must be positive number or zero")
+	ErrDrainTimeoutExceedsTimeout     = errors.New("app.shutdown.drain_timeout must not exceed app.shutdown.timeout")
+	ErrInvalidRestartAttempts         = errors.New("app.restart.attempts must be positive number or zero")
+	ErrInvalidWatchInterval           = errors.New("app.reload.watch_interval must be positive number or zero")
+	ErrInvalidHealthInterval          = errors.New("app.health.interval must be positive number or zero")
+	ErrUnknownKey                     = errors.New("unknown key")
+	ErrLeaderBackendNotConfigured     = errors.New("app.leader.backend connection is not configured")
+	ErrRequiredNotConfigured          = errors.New("connection is not configured")
 
 	// ErrInvalidConfigExtension is returned when parsing a config from a file
 	// when the file has an unsupported extension.
@@ -28,73 +46,465 @@ var (
 // Config is main service config structure.
 type Config struct {
 	App struct {
-		Port                     string         `json:"port" yaml:"port"`
-		ProfilerAddr             string         `json:"profiler_addr" yaml:"profiler_addr"`
-		CheckConnectionsInterval time.Duration  `json:"check_connections_interval" yaml:"check_connections_interval"`
-		ErrorBuffer              int            `json:"error_buffer" yaml:"error_buffer"`
-		Log                      logutil.Config `json:"log" yaml:"log"`
-	} `json:"app" yaml:"app"`
+		Port                     string        `json:"port" yaml:"port" required:"true"`
+		ProfilerAddr             string        `json:"profiler_addr" yaml:"profiler_addr"`
+		CheckConnectionsInterval time.Duration `json:"check_connections_interval" yaml:"check_connections_interval" required:"true"`
+		// CheckConnectionsTimeout is the time given to the concurrent
+		// connections checks.
+		CheckConnectionsTimeout time.Duration  `json:"check_connections_timeout" yaml:"check_connections_timeout"`
+		ErrorBuffer             int            `json:"error_buffer" yaml:"error_buffer" required:"true"`
+		Log                     logutil.Config `json:"log" yaml:"log"`
+		HTTP                    http.Config    `json:"http" yaml:"http"`
+		Leader                  leader.Config  `json:"leader" yaml:"leader"`
+		Health                  struct {
+			// Required contains connections which must be available for
+			// the readiness probe to pass: postgres, clickhouse,
+			// elasticsearch, redis or rabbitmq for the default instances
+			// and driver joined with instance name by dot for the named
+			// ones, e.g. postgres.analytics. Empty list means all
+			// configured connections are required.
+			Required []string `json:"required" yaml:"required"`
+
+			// Interval is the interval of the connections checks which
+			// refresh the readiness probe report. The probe is served from
+			// the last check, so it is stale up to the interval.
+			Interval time.Duration `json:"interval" yaml:"interval"`
+		} `json:"health" yaml:"health"`
+		Reload struct {
+			// WatchInterval is the interval of config file changes check.
+			// Zero disables the file watching, the config is reloaded on
+			// SIGHUP only.
+			WatchInterval time.Duration `json:"watch_interval" yaml:"watch_interval"`
+		} `json:"reload" yaml:"reload"`
+		Migrate struct {
+			// OnStart enables applying of the pending embedded migrations
+			// to the configured databases on the service start.
+			OnStart bool `json:"on_start" yaml:"on_start"`
+		} `json:"migrate" yaml:"migrate"`
+		Shutdown struct {
+			// Timeout is the time given to each component to stop.
+			Timeout time.Duration `json:"timeout" yaml:"timeout"`
+			// DrainDelay is the time the service keeps serving requests
+			// after the readiness probe starts to fail, so load balancers
+			// have time to exclude the instance.
+			DrainDelay time.Duration `json:"drain_delay" yaml:"drain_delay"`
+			// DrainTimeout is the time given to components to finish
+			// in-flight work, e.g. HTTP requests and consumed messages.
+			// It must not exceed Timeout.
+			DrainTimeout time.Duration `json:"drain_timeout" yaml:"drain_timeout"`
+		} `json:"shutdown" yaml:"shutdown"`
+		Restart struct {
+			// Attempts is the number of retries and restarts of the failed
+			// error source before the service termination.
+			Attempts int            `json:"attempts" yaml:"attempts"`
+			Backoff  backoff.Config `json:"backoff" yaml:"backoff"`
+		} `json:"restart" yaml:"restart"`
+		Errors struct {
+			// Default is the action for sources without policy: log or
+			// shutdown.
+			Default Action `json:"default" yaml:"default"`
+			// Policies contains actions by error sources: http, profiler,
+			// connector and other daemon components. Retry is supported
+			// by the connector only, restart by components only.
+			Policies map[string]Action `json:"policies" yaml:"policies"`
+		} `json:"errors" yaml:"errors"`
+	} `json:"app" yaml:"app" required:"true"`
 	Connections connector.Config `yaml:"connections" json:"connections"`
+
+	files     []string
+	envPrefix string
+	overrides []configutil.Override
+
+	// unknownKeys contains keys of the config files which do not match any
+	// config field. They are reported by Validate.
+	unknownKeys []error
 }
 
-// NewConfig creates new config from `name` file data.
-func NewConfig(name string) (*Config, error) {
+// NewConfig creates new config from files data. Files are deep merged in
+// order, so the following files override values of the previous ones.
+// Defaults are not set: SetDefaults must be called after the environment
+// variables and secrets are applied, so sections set by them get defaults too.
+func NewConfig(names ...string) (*Config, error) {
 	cfg := new(Config)
-	if err := cfg.ParseFromFile(name); err != nil {
-		return nil, err
+
+	for _, name := range names {
+		if err := cfg.ParseFromFile(name); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
 	}
 
+	cfg.files = names
+
 	return cfg, nil
 }
 
-// ParseFromFile reads config text data and binds to config struct.
+// Files returns names of the files the config was created from.
+func (cfg *Config) Files() []string {
+	return cfg.files
+}
+
+// Reload creates new config from the same files and environment variables
+// prefix as the current one. Secrets are resolved and defaults are set.
+func (cfg *Config) Reload() (*Config, error) {
+	c, err := NewConfig(cfg.files...)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.envPrefix != "" {
+		if err := c.ParseFromEnv(cfg.envPrefix); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.ResolveSecrets(); err != nil {
+		return nil, err
+	}
+
+	c.SetDefaults()
+
+	return c, nil
+}
+
+// ResolveSecrets replaces secret references like file:///run/secrets/pg or
+// env://PG_PASS in the secret fields (passwords and URLs with credentials) by
+// the secret values. It must be called after the file and env parsing.
+func (cfg *Config) ResolveSecrets() error {
+	if err := configutil.ResolveSecrets(cfg); err != nil {
+		return fmt.Errorf("resolve secrets: %w", err)
+	}
+
+	return nil
+}
+
+// Redacted returns a copy of the config with secret fields redacted. The copy
+// is safe to print or expose.
+func (cfg *Config) Redacted() (*Config, error) {
+	// Deep copy the config, so redaction does not change maps of the original.
+	js, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("copy config: %w", err)
+	}
+
+	c := Config{files: cfg.files, envPrefix: cfg.envPrefix, overrides: cfg.overrides}
+	if err := json.Unmarshal(js, &c); err != nil {
+		return nil, fmt.Errorf("copy config: %w", err)
+	}
+
+	configutil.Redact(&c)
+
+	return &c, nil
+}
+
+// ParseFromEnv overrides config fields with environment variables. Variable
+// name is built from the prefix and yaml keys of the field path joined with
+// underscore and converted to upper case, e.g. GOSERVICE_APP_PORT or
+// GOSERVICE_CONNECTIONS_POSTGRES_PASSWORD. Environment variables take
+// precedence over the config file values.
+func (cfg *Config) ParseFromEnv(prefix string) error {
+	overrides, err := configutil.ApplyEnv(cfg, prefix, os.Environ())
+	if err != nil {
+		return fmt.Errorf("parse env: %w", err)
+	}
+
+	cfg.envPrefix = prefix
+	cfg.overrides = overrides
+
+	return nil
+}
+
+// ParseFromFile reads config text data and merges it into config struct.
+// Structs and maps, e.g. rabbit consumers and publishers, are merged by keys,
+// lists and other values present in the file are replaced.
 func (cfg *Config) ParseFromFile(name string) error {
+	next, tree, tag, err := decodeFile(name)
+	if err != nil {
+		return err
+	}
+
+	configutil.Merge(cfg, next, tree, tag)
+
+	for _, key := range configutil.UnknownKeys(cfg, tree, tag) {
+		cfg.unknownKeys = append(cfg.unknownKeys, multierror.Field(key, fmt.Errorf("%w in %s", ErrUnknownKey, name)))
+	}
+
+	return nil
+}
+
+// decodeFile decodes the file to the generic tree and to the config by the
+// decoder registered for the file extension (see configutil.RegisterDecoder).
+// Returns the struct tag the file keys correspond to. The tree is returned
+// even if the file cannot be decoded to the config.
+func decodeFile(name string) (*Config, interface{}, string, error) {
+	decoder, ok := configutil.LookupDecoder(path.Ext(name))
+	if !ok {
+		return nil, nil, "", fmt.Errorf("%w: %s, supported: %s", ErrInvalidConfigExtension,
+			path.Ext(name), strings.Join(configutil.Extensions(), ", "))
+	}
+
 	file, err := ioutil.ReadFile(name)
 	if err != nil {
-		return fmt.Errorf("read file: %w", err)
+		return nil, nil, "", fmt.Errorf("read file: %w", err)
 	}
 
-	switch ext := path.Ext(name); ext {
-	case ".yaml":
-		err = yaml.Unmarshal(file, cfg)
-	case ".json":
-		err = json.Unmarshal(file, cfg)
-	default:
-		err = fmt.Errorf("%w: %s", ErrInvalidConfigExtension, ext)
+	var cfg Config
+
+	tree, err := decoder.Decode(file, &cfg)
+	if err != nil {
+		return nil, tree, decoder.Tag(), err
 	}
 
-	return err
+	return &cfg, tree, decoder.Tag(), nil
+}
+
+// Schema returns JSON Schema of the config file. The tag selects keys and
+// durations format of the file: yaml or json.
+func Schema(tag string) *configutil.Schema {
+	return configutil.NewSchema(new(Config), tag)
 }
 
-// Validate checks config to required fields.
+// ValidateFiles checks config files offline without connecting to anything.
+// Values of each file are checked against the schema, then the merged config
+// with environment variables overrides (if envPrefix is not empty) and
+// defaults is validated. All found problems are returned at once.
+func ValidateFiles(envPrefix string, names ...string) error {
+	errs := multierror.New()
+	decoded := true
+
+	for _, name := range names {
+		_, tree, tag, err := decodeFile(name)
+		if tree != nil {
+			if err := Schema(tag).Validate(tree); err != nil {
+				schemaErrs := []error{err}
+
+				var merr multierror.Error
+				if errors.As(err, &merr) {
+					schemaErrs = merr.Errors()
+				}
+
+				for _, e := range schemaErrs {
+					errs.Append(fmt.Errorf("%s: %w", name, e))
+				}
+			}
+		}
+
+		if err != nil {
+			errs.Append(fmt.Errorf("%s: %w", name, err))
+
+			decoded = false
+		}
+	}
+
+	if !decoded {
+		// Merged config cannot be validated.
+		return errs
+	}
+
+	cfg, err := NewConfig(names...)
+	if err != nil {
+		return err
+	}
+
+	if envPrefix != "" {
+		if err := cfg.ParseFromEnv(envPrefix); err != nil {
+			return err
+		}
+	}
+
+	cfg.SetDefaults()
+	errs.Append(cfg.Validate())
+
+	if errs.Len() != 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// SetDefaults sets default values of the fields which are not set. It must be
+// called after the environment variables and secrets are applied. Defaults
+// are visible on the config print.
+func (cfg *Config) SetDefaults() {
+	if cfg.App.CheckConnectionsTimeout == 0 {
+		cfg.App.CheckConnectionsTimeout = connector.DefaultCheckTimeout
+	}
+
+	if cfg.App.Shutdown.Timeout == 0 {
+		cfg.App.Shutdown.Timeout = DefaultShutdownTimeout
+	}
+
+	// Default drain timeout must not exceed the shutdown timeout set shorter
+	// than the default one.
+	if cfg.App.Shutdown.DrainTimeout == 0 {
+		cfg.App.Shutdown.DrainTimeout = DefaultShutdownTimeout
+		if cfg.App.Shutdown.Timeout > 0 && cfg.App.Shutdown.Timeout < DefaultShutdownTimeout {
+			cfg.App.Shutdown.DrainTimeout = cfg.App.Shutdown.Timeout
+		}
+	}
+
+	if cfg.App.Health.Interval == 0 {
+		cfg.App.Health.Interval = DefaultHealthInterval
+	}
+
+	if cfg.App.Errors.Default == "" {
+		cfg.App.Errors.Default = ActionShutdown
+	}
+
+	cfg.App.Log.SetDefaults()
+	cfg.App.HTTP.SetDefaults()
+	cfg.App.Leader.SetDefaults()
+	cfg.App.Restart.Backoff.SetDefaults()
+	cfg.Connections.SetDefaults()
+}
+
+// Validate checks config to required fields. All found problems are
+// returned at once as multierror.Error with dotted paths of the fields,
+// including keys of the config files which do not match any field.
 func (cfg *Config) Validate() error {
+	errs := multierror.New()
+
+	for _, err := range cfg.unknownKeys {
+		errs.Append(err)
+	}
+
 	if cfg.App.Port == "" {
-		return ErrEmptyPort
+		errs.Append(ErrEmptyPort)
 	}
 
 	if cfg.App.CheckConnectionsInterval == 0 {
-		return ErrEmptyCheckConnectionsInterval
+		errs.Append(ErrEmptyCheckConnectionsInterval)
+	}
+
+	if cfg.App.CheckConnectionsTimeout <= 0 {
+		errs.Append(ErrInvalidCheckConnectionsTimeout)
 	}
 
 	if cfg.App.ErrorBuffer == 0 {
-		return ErrEmptyErrorBuffer
+		errs.Append(ErrEmptyErrorBuffer)
+	}
+
+	if cfg.App.Shutdown.Timeout < 0 {
+		errs.Append(ErrInvalidShutdownTimeout)
+	}
+
+	if cfg.App.Shutdown.DrainDelay < 0 {
+		errs.Append(ErrInvalidDrainDelay)
 	}
 
-	if err := cfg.Connections.Validate(); err != nil {
-		return fmt.Errorf("connections: %w", err)
+	if cfg.App.Shutdown.DrainTimeout < 0 {
+		errs.Append(ErrInvalidDrainTimeout)
+	}
+
+	// HTTP server waits for in-flight requests drain_timeout on stop, which
+	// is limited by timeout.
+	if cfg.App.Shutdown.DrainTimeout > cfg.App.Shutdown.Timeout && cfg.App.Shutdown.Timeout > 0 {
+		errs.Append(ErrDrainTimeoutExceedsTimeout)
+	}
+
+	errs.Append(multierror.Prefix(cfg.App.HTTP.Validate(), "app.http"))
+	errs.Append(multierror.Prefix(cfg.App.Leader.Validate(), "app.leader"))
+
+	if cfg.App.Leader.Enabled && !cfg.isLeaderBackendConfigured() {
+		errs.Append(ErrLeaderBackendNotConfigured)
+	}
+
+	enabled := cfg.Connections.Enabled()
+
+	for i, name := range cfg.App.Health.Required {
+		if !contains(enabled, name) {
+			errs.Append(multierror.Field(fmt.Sprintf("app.health.required[%d]", i), fmt.Errorf("%s %w", name, ErrRequiredNotConfigured)))
+		}
+	}
+
+	if cfg.App.Health.Interval < 0 {
+		errs.Append(ErrInvalidHealthInterval)
+	}
+
+	if cfg.App.Reload.WatchInterval < 0 {
+		errs.Append(ErrInvalidWatchInterval)
+	}
+
+	if cfg.App.Restart.Attempts < 0 {
+		errs.Append(ErrInvalidRestartAttempts)
+	}
+
+	errs.Append(multierror.Prefix(cfg.App.Restart.Backoff.Validate(), "app.restart.backoff"))
+
+	switch cfg.App.Errors.Default {
+	case "", ActionLog, ActionShutdown:
+	default:
+		// Default action is applied to the connector and components, so
+		// only actions supported by all of them are allowed.
+		errs.Append(multierror.Field("app.errors.default", ErrInvalidDefaultAction))
+	}
+
+	sources := make([]string, 0, len(cfg.App.Errors.Policies))
+	for source := range cfg.App.Errors.Policies {
+		sources = append(sources, source)
+	}
+
+	sort.Strings(sources)
+
+	for _, source := range sources {
+		errs.Append(multierror.Field("app.errors.policies."+source, validatePolicy(source, cfg.App.Errors.Policies[source])))
+	}
+
+	errs.Append(multierror.Prefix(cfg.Connections.Validate(), "connections"))
+
+	if errs.Len() != 0 {
+		return errs
 	}
 
 	return nil
 }
 
-// Print print config to console.
-func (cfg *Config) Print(w io.Writer) error {
-	js, err := json.MarshalIndent(cfg, "", "  ")
+// Print print config to console as JSON document. Secrets are redacted.
+// Sources of the effective values are written to info, e.g. stderr, so the
+// document stays valid JSON.
+func (cfg *Config) Print(w, info io.Writer) error {
+	redacted, err := cfg.Redacted()
+	if err != nil {
+		return fmt.Errorf("print config: %w", err)
+	}
+
+	js, err := json.MarshalIndent(redacted, "", "  ")
 	if err != nil {
 		return fmt.Errorf("print config: %w", err)
 	}
 
 	fmt.Fprintln(w, string(js))
 
+	for _, name := range cfg.files {
+		fmt.Fprintf(info, "# file: %s\n", name)
+	}
+
+	for _, o := range cfg.overrides {
+		fmt.Fprintf(info, "# env: %s -> %s\n", o.Env, o.Path)
+	}
+
 	return nil
 }
+
+// isLeaderBackendConfigured reports whether the connection used by the leader
+// election backend is enabled.
+func (cfg *Config) isLeaderBackendConfigured() bool {
+	switch cfg.App.Leader.Backend {
+	case leader.BackendRedis:
+		return cfg.Connections.Redis.IsEnabled()
+	case leader.BackendPostgres:
+		return cfg.Connections.Postgres.IsEnabled()
+	default:
+		// Invalid backend is reported by the leader config validation.
+		return true
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
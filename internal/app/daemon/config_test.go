@@ -0,0 +1,103 @@
+package daemon
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/outdead/goservice/internal/utils/driver/postgres"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_SetDefaults_DrainTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		drain   time.Duration
+		want    time.Duration
+	}{
+		{"defaults", 0, 0, DefaultShutdownTimeout},
+		{"short timeout", 5 * time.Second, 0, 5 * time.Second},
+		{"long timeout", time.Minute, 0, DefaultShutdownTimeout},
+		{"drain is set", 5 * time.Second, 3 * time.Second, 3 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := new(Config)
+			cfg.App.Port = "8080"
+			cfg.App.CheckConnectionsInterval = time.Minute
+			cfg.App.ErrorBuffer = 10
+			cfg.App.Shutdown.Timeout = tt.timeout
+			cfg.App.Shutdown.DrainTimeout = tt.drain
+			cfg.SetDefaults()
+
+			assert.Equal(t, tt.want, cfg.App.Shutdown.DrainTimeout)
+			assert.NoError(t, cfg.Validate())
+		})
+	}
+}
+
+func TestConfig_Reload_EnvDefaults(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "config.yaml")
+	if err := ioutil.WriteFile(name, []byte("app:\n  port: 8080\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Setenv("DAEMONTEST_CONNECTIONS_POSTGRES_ADDR", "localhost:5432"); err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.Unsetenv("DAEMONTEST_CONNECTIONS_POSTGRES_ADDR")
+
+	cfg, err := NewConfig(name)
+	if !assert.NoError(t, err) || !assert.NoError(t, cfg.ParseFromEnv("DAEMONTEST")) {
+		return
+	}
+
+	cfg, err = cfg.Reload()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// Section set by environment variables only gets defaults too.
+	if assert.NotNil(t, cfg.Connections.Postgres) {
+		assert.Equal(t, "localhost:5432", cfg.Connections.Postgres.Addr)
+		assert.Equal(t, postgres.DefaultPoolTimeout, cfg.Connections.Postgres.PoolTimeout)
+	}
+}
+
+func TestConfig_Validate_HealthInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+		want     time.Duration
+		wantErr  bool
+	}{
+		{"default", 0, DefaultHealthInterval, false},
+		{"set", time.Second, time.Second, false},
+		{"negative", -time.Second, -time.Second, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := new(Config)
+			cfg.App.Port = "8080"
+			cfg.App.CheckConnectionsInterval = time.Minute
+			cfg.App.ErrorBuffer = 10
+			cfg.App.Health.Interval = tt.interval
+			cfg.SetDefaults()
+
+			assert.Equal(t, tt.want, cfg.App.Health.Interval)
+
+			err := cfg.Validate()
+			if tt.wantErr {
+				assert.Contains(t, err.Error(), ErrInvalidHealthInterval.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -1,15 +1,22 @@
 package daemon
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/outdead/goservice/internal/app/server/http"
 	"github.com/outdead/goservice/internal/app/server/profiler"
 	"github.com/outdead/goservice/internal/connector"
+	"github.com/outdead/goservice/internal/migrations"
+	"github.com/outdead/goservice/internal/utils/driver/postgres"
+	"github.com/outdead/goservice/internal/utils/driver/redis"
+	"github.com/outdead/goservice/internal/utils/errclass"
+	"github.com/outdead/goservice/internal/utils/leader"
 	"github.com/outdead/goservice/internal/utils/logutil"
 )
 
@@ -19,18 +26,59 @@ type Daemon struct {
 	logger *logutil.Entry
 	errors chan error
 
-	conn   connector.Connector
-	server struct {
-		http *http.Server
-	}
+	// ctx is the root context of the Daemon which is canceled on interrupt
+	// and Close. It aborts startup retries, migrations, connections checks
+	// and leader election in progress.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	conn       connector.Connector
+	leader     *leader.Elector
+	components []Component
+	started    []*runningComponent
+
+	componentErrors chan error
+	errorCounter    *errclass.Counter
+	retriers        map[string]*retrier
+	retries         chan string
+	restarts        chan string
+
+	draining int32
+	health   healthReport
+
+	refresher      *time.Ticker
+	prober         *time.Ticker
+	watcher        *time.Ticker
+	watcherC       <-chan time.Time
+	configModTimes []time.Time
 }
 
 // NewDaemon creates new Daemon.
 func NewDaemon(cfg *Config, log *logutil.Entry) *Daemon {
+	if log == nil {
+		log = logutil.New().NewEntry()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
 	d := Daemon{
 		config: cfg,
 		errors: make(chan error, cfg.App.ErrorBuffer),
 		logger: log,
+		ctx:    ctx,
+		cancel: cancel,
+
+		componentErrors: make(chan error),
+		errorCounter:    errclass.NewCounter(),
+		retriers:        make(map[string]*retrier),
+	}
+
+	d.health.setRequired(cfg.App.Health.Required)
+
+	if cfg.App.Leader.Enabled {
+		// Backend is set on init when connections are established.
+		d.leader = leader.NewElector(&cfg.App.Leader, nil, log)
+		d.leader.SetContext(ctx)
 	}
 
 	return &d
@@ -43,85 +91,258 @@ func (d *Daemon) Close() error {
 
 // Run starts the Daemon.
 func (d *Daemon) Run() error {
+	interrupter := make(chan os.Signal, 1)
+	signal.Notify(interrupter, syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
+
+	defer signal.Stop(interrupter)
+
+	// Interrupt is watched from the start, so it aborts waiting for the
+	// connections and migrations too.
+	go func() {
+		select {
+		case <-interrupter:
+			d.logger.Info("received an interrupt, unsubscribe and closing connections...")
+			d.cancel()
+		case <-d.ctx.Done():
+		}
+	}()
+
 	if err := d.init(); err != nil {
+		if d.ctx.Err() != nil {
+			d.logger.WithError(err).Info("start daemon interrupted")
+
+			return nil
+		}
+
 		return err
 	}
 
-	// Creates goroutine process for start profiler.
-	profiler.Serve(d.config.App.ProfilerAddr, d.logger)
+	// Starts HTTP server, profiler and registered components.
+	if err := d.startComponents(); err != nil {
+		return err
+	}
 
-	// Creates goroutine process for start HTTP server.
-	d.server.http.Serve(d.config.App.Port)
+	reloader := make(chan os.Signal, 1)
+	signal.Notify(reloader, syscall.SIGHUP)
 
-	interrupter := make(chan os.Signal, 1)
-	signal.Notify(interrupter, syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
+	defer signal.Stop(reloader)
+
+	d.refresher = time.NewTicker(d.config.App.CheckConnectionsInterval)
+	defer d.refresher.Stop()
+
+	d.prober = time.NewTicker(d.config.App.Health.Interval)
+	defer d.prober.Stop()
 
-	refresher := time.NewTicker(d.config.App.CheckConnectionsInterval)
-	defer refresher.Stop()
+	// Watcher channel is nil if the config file watching is disabled, so
+	// the select case below is never chosen.
+	d.watchConfig()
+
+	defer func() {
+		if d.watcher != nil {
+			d.watcher.Stop()
+		}
+	}()
 
 	d.logger.Info("start daemon success")
 
 Loop:
 	for {
 		select {
-		case <-interrupter:
-			d.logger.Info("received an interrupt, unsubscribe and closing connections...")
-
+		case <-d.ctx.Done():
 			break Loop
 		case err := <-d.Errors(): // down here
 			d.logger.Info("daemon fatal error occurred, unsubscribe and closing connections...")
 
 			return err
-		case <-refresher.C:
+		case <-d.refresher.C:
 			d.logger.Debug("check connections")
 
-			// If an error is received, a fatal error is reported and the service is terminated.
-			if err := d.conn.CheckConnections(); err != nil {
-				d.reportError(err)
+			d.checkConnections()
+		case <-d.prober.C:
+			d.refreshHealth()
+		case <-reloader:
+			d.logger.Info("received SIGHUP")
+
+			d.reload()
+		case <-d.watcherC:
+			changed, err := d.isConfigChanged()
+			if err != nil {
+				d.logger.WithError(err).Error("watch config error")
+
+				continue
 			}
-		// Getting errors from daemon-controlled connections and goroutines.
-		// For now, errors are transferred to daemon's error channel, which
-		// initiates the application termination. But in the future, here
-		// you can do the processing of each error with a decision on what
-		// to do with it.
-		case err := <-d.server.http.Errors():
-			d.reportError(err) // TODO: Try to recreate http server.
+
+			if changed {
+				d.logger.Info("config file changed")
+
+				d.reload()
+			}
+		// Getting errors from daemon-controlled components. Errors are
+		// processed according to app.errors policies. Errors which lead to
+		// shutdown are transferred to daemon's error channel.
+		case err := <-d.componentErrors:
+			d.handleComponentError(err)
+		case source := <-d.retries:
+			d.retry(source)
+		case name := <-d.restarts:
+			d.restartComponent(name)
 		}
 	}
 
 	return nil
 }
 
+// Leader returns leader Elector which allows you to run singleton work on one
+// of the service replicas, e.g.
+//
+//	d.Leader().Bind(tickerprocess.NewProcess(cfg, repo, log))
+//
+// Returns nil if app.leader is not enabled.
+func (d *Daemon) Leader() *leader.Elector {
+	return d.leader
+}
+
+// IsDraining returns true if the Daemon is shutting down and must not receive
+// new requests.
+func (d *Daemon) IsDraining() bool {
+	return atomic.LoadInt32(&d.draining) == 1
+}
+
 // Errors returns daemon error channel.
 func (d *Daemon) Errors() <-chan error {
 	return d.errors
 }
 
 func (d *Daemon) init() error {
-	if d.logger == nil {
-		d.logger = logutil.New().NewEntry()
-	}
-
 	var err error
 
-	if d.conn, err = connector.New(&d.config.Connections); err != nil {
+	d.conn, err = connector.New(&d.config.Connections, connector.SetLogger(d.logger), connector.SetContext(d.ctx))
+	if err != nil {
 		return fmt.Errorf("connector: %w", err)
 	}
 
-	d.server.http = http.NewServer(d.logger)
+	// Readiness probe is served from the first check until the periodic
+	// ones. Its errors are handled by the periodic checks.
+	report, _ := d.checkReport()
+	d.health.set(report)
+
+	if d.config.App.Migrate.OnStart {
+		if err := d.migrate(); err != nil {
+			return fmt.Errorf("migrate: %w", err)
+		}
+	}
+
+	// Built-in components are started before the registered ones and are
+	// stopped after them.
+	builtin := []Component{d.newHTTPComponent()}
+
+	if d.config.App.ProfilerAddr != "" {
+		builtin = append(builtin, d.newProfilerComponent())
+	}
+
+	if d.leader != nil {
+		backend, err := d.newLeaderBackend()
+		if err != nil {
+			return fmt.Errorf("leader: %w", err)
+		}
+
+		d.leader.SetBackend(backend)
+		builtin = append(builtin, d.leader)
+	}
+
+	d.components = append(builtin, d.components...)
+
+	// Only one retry of each source can be pending so the buffers never block
+	// the retry timers.
+	d.retries = make(chan string, 1)
+	d.restarts = make(chan string, len(d.components))
 
 	return nil
 }
 
+// migrate applies pending migrations to the configured databases.
+func (d *Daemon) migrate() error {
+	migrators, err := migrations.NewMigrators(d.conn)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrators {
+		applied, err := m.Up(d.ctx)
+		for _, migration := range applied {
+			d.logger.Infof("%s: migration %d_%s applied", m.Name(), migration.Version, migration.Name)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *Daemon) newHTTPComponent() Component {
+	server := http.NewServer(d.logger,
+		http.SetConfig(&d.config.App.HTTP),
+		http.SetErrorCounter(d.errorCounter),
+		http.SetDraining(d.IsDraining),
+		http.SetDependencies(d.dependencies),
+		http.SetShutdownTimeout(d.config.App.Shutdown.DrainTimeout),
+	)
+
+	return NewComponent("http", func() error {
+		// Config can be changed by reload between restarts.
+		server.SetConfig(&d.config.App.HTTP)
+		server.Serve(d.config.App.Port)
+
+		return nil
+	}, server.Close, server.Errors())
+}
+
+func (d *Daemon) newLeaderBackend() (leader.Backend, error) {
+	cfg := &d.config.App.Leader
+
+	// Connections are resolved on every lock operation because they can be
+	// replaced by reconnection and reload. Absent connection is checked
+	// once on start.
+	if cfg.Backend == leader.BackendRedis {
+		if _, err := d.conn.Redis(); err != nil {
+			return nil, err
+		}
+
+		return leader.NewRedisBackend(func() (redis.Store, error) { return d.conn.Redis() }, cfg.Key, cfg.TTL), nil
+	}
+
+	if _, err := d.conn.PG(); err != nil {
+		return nil, err
+	}
+
+	return leader.NewPostgresBackend(func() (postgres.Database, error) { return d.conn.PG() }, cfg.Key), nil
+}
+
+func (d *Daemon) newProfilerComponent() Component {
+	server := profiler.NewServer(d.logger)
+
+	return NewComponent("profiler", func() error {
+		server.Serve(d.config.App.ProfilerAddr)
+
+		return nil
+	}, server.Close, server.Errors())
+}
+
 func (d *Daemon) close() error {
 	d.logger.Debug("stopping daemon...")
 
+	d.cancel()
+
 	var errs []error
 
-	if d.server.http != nil {
-		if err := d.server.http.Close(); err != nil {
-			errs = append(errs, err)
-		}
+	if err := d.drain(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := d.stopComponents(); err != nil {
+		errs = append(errs, err)
 	}
 
 	if d.conn != nil {
@@ -145,17 +366,33 @@ func (d *Daemon) close() error {
 	return nil
 }
 
-// reportError publishes error to the errors channel.
-// if you do not read errors from the errors channel then after the channel
-// buffer overflows the application exits with a fatal level and the
-// os.Exit(1) exit code.
+// drain marks the Daemon as not ready, keeps serving for app.shutdown.drain_delay
+// and stops components from taking new work.
+func (d *Daemon) drain() error {
+	if len(d.started) == 0 {
+		return nil
+	}
+
+	atomic.StoreInt32(&d.draining, 1)
+
+	if delay := d.config.App.Shutdown.DrainDelay; delay > 0 {
+		d.logger.Infof("draining, keep serving for %s...", delay)
+
+		time.Sleep(delay)
+	}
+
+	return d.drainComponents()
+}
+
+// reportError publishes error to the errors channel which initiates the
+// service shutdown. If the channel buffer is full the shutdown is already in
+// progress, so the error is only logged.
 func (d *Daemon) reportError(err error) {
 	if err != nil {
 		select {
 		case d.errors <- err:
 		default:
-			// IMPORTANT: This is a soft version of the application panic.
-			d.logger.Fatalf("daemon error channel is locked: %v", err)
+			d.logger.Errorf("daemon error channel is full: %v", err)
 		}
 	}
 }
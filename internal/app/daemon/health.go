@@ -0,0 +1,88 @@
+package daemon
+
+import (
+	"sync"
+	"time"
+
+	"github.com/outdead/goservice/internal/app/server/http/handler/system"
+	"github.com/outdead/goservice/internal/connector"
+)
+
+// DefaultHealthInterval is the interval of the readiness report refresh if
+// app.health.interval is not set.
+const DefaultHealthInterval = 10 * time.Second
+
+// healthReport keeps the last connections check report. Readiness probes are
+// served from it, so they do not open connections to the databases on every
+// request of the unauthenticated endpoint. The list of the required
+// connections is kept here too because probes are served by HTTP goroutines
+// while the config is swapped by reload.
+type healthReport struct {
+	mu        sync.RWMutex
+	report    connector.Report
+	checkedAt time.Time
+	required  []string
+}
+
+// set replaces the report by the one checked now.
+func (h *healthReport) set(report connector.Report) {
+	h.mu.Lock()
+	h.report, h.checkedAt = report, time.Now()
+	h.mu.Unlock()
+}
+
+// setRequired replaces the list of the required connections.
+func (h *healthReport) setRequired(required []string) {
+	h.mu.Lock()
+	h.required = required
+	h.mu.Unlock()
+}
+
+// get returns the last report, the time of the check and the list of the
+// required connections.
+func (h *healthReport) get() (connector.Report, time.Time, []string) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.report, h.checkedAt, h.required
+}
+
+// dependencies returns status of the connections from the last check for the
+// readiness probe. Connections listed in app.health.required or all of them
+// if the list is empty are required.
+func (d *Daemon) dependencies() []system.Dependency {
+	report, checkedAt, required := d.health.get()
+
+	deps := make([]system.Dependency, 0, len(report))
+
+	for _, status := range report {
+		dep := system.Dependency{
+			Name:      status.Name,
+			OK:        status.OK,
+			Required:  len(required) == 0 || contains(required, status.Name),
+			Latency:   status.Latency.String(),
+			CheckedAt: checkedAt,
+		}
+
+		if status.Err != nil {
+			dep.Error = status.Err.Error()
+		}
+
+		deps = append(deps, dep)
+	}
+
+	return deps
+}
+
+// refreshHealth checks connector connections and keeps the report for the
+// readiness probe. Errors are handled by the periodic connections checks, so
+// the probe is refreshed more often than check_connections_interval without
+// additional retries of the connector.
+func (d *Daemon) refreshHealth() {
+	report, _ := d.checkReport()
+	if d.ctx.Err() != nil {
+		return
+	}
+
+	d.health.set(report)
+}
@@ -0,0 +1,50 @@
+package daemon
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/outdead/goservice/internal/connector"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDaemon_Dependencies_Reload(t *testing.T) {
+	d := newTestDaemon(t)
+	d.config.App.Health.Required = []string{"postgres"}
+	d.health.setRequired(d.config.App.Health.Required)
+	d.health.set(connector.Report{{Name: "postgres", OK: true}, {Name: "redis"}})
+
+	required := func() map[string]bool {
+		res := make(map[string]bool)
+		for _, dep := range d.dependencies() {
+			res[dep.Name] = dep.Required
+		}
+
+		return res
+	}
+
+	assert.Equal(t, map[string]bool{"postgres": true, "redis": false}, required())
+
+	// Probes are served while the config is swapped by reload.
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		for i := 0; i < 100; i++ {
+			d.dependencies()
+		}
+	}()
+
+	old := d.config
+	cfg := *old
+	cfg.App.Health.Required = nil
+	d.config = &cfg
+	d.applyConfig(old)
+
+	wg.Wait()
+
+	assert.Equal(t, map[string]bool{"postgres": true, "redis": true}, required())
+}
@@ -0,0 +1,181 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/outdead/goservice/internal/connector"
+	"github.com/outdead/goservice/internal/utils/errclass"
+)
+
+// SourceConnector is the source name of connections check errors. Other
+// sources are named by the daemon components, e.g. http, profiler.
+const SourceConnector = "connector"
+
+// Action describes what the Daemon does with the received error.
+type Action string
+
+// Error policy actions.
+const (
+	// ActionLog logs the error and continues to work.
+	ActionLog Action = "log"
+
+	// ActionRetry repeats the connections check after backoff delay. It is
+	// supported by the connector only.
+	ActionRetry Action = "retry"
+
+	// ActionRestart stops and starts again the failed component after
+	// backoff delay. It is not supported by the connector.
+	ActionRestart Action = "restart"
+
+	// ActionShutdown gracefully terminates the service.
+	ActionShutdown Action = "shutdown"
+)
+
+// Policy validation errors.
+var (
+	ErrInvalidAction        = errors.New("invalid error policy action")
+	ErrInvalidDefaultAction = errors.New("default must be log or shutdown")
+	ErrRetryNotSupported    = errors.New("retry is supported by connector only, use restart")
+	ErrRestartNotSupported  = errors.New("connector cannot be restarted, use retry")
+)
+
+// defaultPolicies contains actions for the sources which are not set in
+// app.errors.policies config.
+var defaultPolicies = map[string]Action{
+	"http": ActionRestart,
+	// Profiler is optional and its failures must not stop the service.
+	"profiler": ActionLog,
+	// Failed lock operations are repeated by the elector itself.
+	"leader": ActionLog,
+}
+
+// Enum returns allowed actions. It is used by the config schema.
+func (a Action) Enum() []string {
+	return []string{string(ActionLog), string(ActionRetry), string(ActionRestart), string(ActionShutdown)}
+}
+
+// Validate checks the action for allowed values.
+func (a Action) Validate() error {
+	switch a {
+	case ActionLog, ActionRetry, ActionRestart, ActionShutdown:
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidAction, a)
+	}
+}
+
+// validatePolicy checks that the action is supported by the source.
+func validatePolicy(source string, action Action) error {
+	if err := action.Validate(); err != nil {
+		return err
+	}
+
+	switch {
+	case source == SourceConnector && action == ActionRestart:
+		return ErrRestartNotSupported
+	case source != SourceConnector && action == ActionRetry:
+		return ErrRetryNotSupported
+	default:
+		return nil
+	}
+}
+
+// policy returns action for errors from the source.
+func (d *Daemon) policy(source string) Action {
+	if action, ok := d.config.App.Errors.Policies[source]; ok {
+		return action
+	}
+
+	if action, ok := defaultPolicies[source]; ok {
+		return action
+	}
+
+	if d.config.App.Errors.Default != "" {
+		return d.config.App.Errors.Default
+	}
+
+	return ActionShutdown
+}
+
+// handleError counts the error and applies the source policy to it. Fatal
+// errors always shut down the service. Transient errors, e.g. response write
+// errors of the clients which went away, are only logged, retries and
+// restarts do not help with them.
+func (d *Daemon) handleError(source string, err error) {
+	class := errclass.Of(err)
+	d.errorCounter.Inc(source, class)
+
+	action := d.policy(source)
+
+	switch class {
+	case errclass.Fatal:
+		action = ActionShutdown
+	case errclass.Transient:
+		action = ActionLog
+	}
+
+	switch action {
+	case ActionLog:
+		entry := d.logger.WithError(err).WithField("source", source)
+
+		if class == errclass.Transient {
+			entry.Warnf("%s error occurred", class)
+		} else {
+			entry.Errorf("%s error occurred", class)
+		}
+	case ActionRetry:
+		d.scheduleRetry(source, err)
+	case ActionRestart:
+		d.scheduleRestart(source, err)
+	default:
+		d.reportError(err)
+	}
+}
+
+// handleComponentError applies the component policy to the error.
+func (d *Daemon) handleComponentError(err error) {
+	var cerr *ComponentError
+	if !errors.As(err, &cerr) {
+		d.reportError(err)
+
+		return
+	}
+
+	d.handleError(cerr.Component, err)
+}
+
+// checkConnections checks connector connections, keeps the report for the
+// readiness probe and handles the error. Checks aborted by the Daemon
+// shutdown are not handled.
+func (d *Daemon) checkConnections() {
+	report, err := d.checkReport()
+	if d.ctx.Err() != nil {
+		return
+	}
+
+	d.health.set(report)
+
+	if err != nil {
+		d.handleError(SourceConnector, err)
+
+		return
+	}
+
+	d.resetRetries(SourceConnector)
+}
+
+// checkReport checks connector connections until app.check_connections_timeout
+// or the Daemon shutdown.
+func (d *Daemon) checkReport() (connector.Report, error) {
+	timeout := d.config.App.CheckConnectionsTimeout
+	if timeout <= 0 {
+		timeout = connector.DefaultCheckTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(d.ctx, timeout)
+	defer cancel()
+
+	return d.conn.CheckConnectionsContext(ctx)
+}
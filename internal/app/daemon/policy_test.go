@@ -0,0 +1,144 @@
+package daemon
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/outdead/goservice/internal/utils/errclass"
+	"github.com/stretchr/testify/assert"
+)
+
+var errSource = errors.New("source error")
+
+// outcome returns the action applied by the Daemon to the handled error.
+func outcome(d *Daemon) Action {
+	timeout := time.After(100 * time.Millisecond)
+
+	select {
+	case <-d.errors:
+		return ActionShutdown
+	case <-d.retries:
+		return ActionRetry
+	case <-d.restarts:
+		return ActionRestart
+	case <-timeout:
+		return ActionLog
+	}
+}
+
+func TestDaemon_HandleError(t *testing.T) {
+	tests := []struct {
+		name     string
+		policies map[string]Action
+		def      Action
+		source   string
+		err      error
+		want     Action
+	}{
+		{"connector retry", map[string]Action{SourceConnector: ActionRetry}, "", SourceConnector, errSource, ActionRetry},
+		{"connector shutdown", map[string]Action{SourceConnector: ActionShutdown}, "", SourceConnector, errSource, ActionShutdown},
+		{"connector log", map[string]Action{SourceConnector: ActionLog}, "", SourceConnector, errSource, ActionLog},
+		{"http default restart", nil, "", "http", errSource, ActionRestart},
+		{"profiler default log", nil, "", "profiler", errSource, ActionLog},
+		{"component restart", map[string]Action{"consumer": ActionRestart}, "", "consumer", errSource, ActionRestart},
+		{"unknown source shutdown", nil, "", "consumer", errSource, ActionShutdown},
+		{"unknown source default log", nil, ActionLog, "consumer", errSource, ActionLog},
+		{"policy overrides default", map[string]Action{"http": ActionShutdown}, "", "http", errSource, ActionShutdown},
+		{"fatal error", map[string]Action{"http": ActionRestart}, "", "http", errclass.Wrap(errSource, errclass.Fatal), ActionShutdown},
+		{"transient error", map[string]Action{"consumer": ActionShutdown}, "", "consumer", errclass.Wrap(errSource, errclass.Transient), ActionLog},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestDaemon(t)
+			d.config.App.Errors.Policies = tt.policies
+			d.config.App.Restart.Attempts = 1
+			d.config.App.Restart.Backoff.InitialInterval = time.Millisecond
+
+			if tt.def != "" {
+				d.config.App.Errors.Default = tt.def
+			}
+
+			d.handleError(tt.source, tt.err)
+
+			assert.Equal(t, tt.want, outcome(d))
+			assert.Len(t, d.errorCounter.Stats().Sources[tt.source], 1, "error is not counted")
+		})
+	}
+}
+
+func TestDaemon_Schedule_Budget(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		action Action
+	}{
+		{"retry", SourceConnector, ActionRetry},
+		{"restart", "http", ActionRestart},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestDaemon(t)
+			d.config.App.Errors.Policies = map[string]Action{tt.source: tt.action}
+			d.config.App.Restart.Attempts = 2
+			d.config.App.Restart.Backoff.InitialInterval = time.Millisecond
+
+			for attempt := 1; attempt <= 2; attempt++ {
+				d.handleError(tt.source, errSource)
+
+				// Errors of the source waiting for retry do not spend the
+				// budget.
+				d.handleError(tt.source, errSource)
+
+				if !assert.Equal(t, tt.action, outcome(d), "attempt %d", attempt) {
+					return
+				}
+
+				d.retried(tt.source)
+			}
+
+			// Exhausted budget shuts down the service.
+			d.handleError(tt.source, errSource)
+			assert.Equal(t, ActionShutdown, outcome(d))
+		})
+	}
+}
+
+func TestDaemon_ResetRetries(t *testing.T) {
+	d := newTestDaemon(t)
+	d.config.App.Errors.Policies = map[string]Action{SourceConnector: ActionRetry}
+	d.config.App.Restart.Attempts = 1
+	d.config.App.Restart.Backoff.InitialInterval = time.Millisecond
+
+	d.handleError(SourceConnector, errSource)
+	assert.Equal(t, ActionRetry, outcome(d))
+
+	// Successful retry restores the budget.
+	d.retried(SourceConnector)
+	d.resetRetries(SourceConnector)
+
+	d.handleError(SourceConnector, errSource)
+	assert.Equal(t, ActionRetry, outcome(d))
+}
+
+func TestDaemon_Schedule_Shutdown(t *testing.T) {
+	d := newTestDaemon(t)
+	d.config.App.Restart.Attempts = 1
+	d.config.App.Restart.Backoff.InitialInterval = 10 * time.Millisecond
+
+	// Run loop does not read the channel after the shutdown.
+	ch := make(chan string)
+
+	d.schedule(SourceConnector, errSource, ch)
+	d.cancel()
+
+	time.Sleep(50 * time.Millisecond)
+
+	select {
+	case <-ch:
+		t.Error("timer goroutine waits for the channel after the shutdown")
+	default:
+	}
+}
@@ -0,0 +1,177 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"time"
+)
+
+// reload re-reads the config files and environment variables and applies
+// changes to the running Daemon. Reload is rejected as a whole if the new
+// config is invalid or connections of the changed connector sections cannot
+// be established. Changes of port, profiler_addr, error_buffers and leader
+// require restart of the service and are not applied.
+func (d *Daemon) reload() {
+	d.logger.Info("reloading config...")
+
+	cfg, err := d.config.Reload()
+	if err != nil {
+		d.logger.WithError(err).Error("reload rejected: new config")
+
+		return
+	}
+
+	if err := cfg.Validate(); err != nil {
+		d.logger.WithError(err).Error("reload rejected: validate config")
+
+		return
+	}
+
+	d.keepRestartOnly(cfg)
+
+	if err := d.conn.Reload(&cfg.Connections); err != nil {
+		d.logger.WithError(err).Error("reload rejected: connector")
+
+		return
+	}
+
+	old := d.config
+	d.config = cfg
+
+	d.applyConfig(old)
+
+	// Connections changed by the reload are reported by the readiness probe
+	// without waiting for the next periodic check.
+	d.checkConnections()
+
+	d.logger.Info("reload config success")
+}
+
+// keepRestartOnly replaces in the new config values which can be changed by
+// the service restart only.
+func (d *Daemon) keepRestartOnly(cfg *Config) {
+	old := &d.config.App
+	app := &cfg.App
+
+	if app.Port != old.Port {
+		d.logger.Warnf("app.port change requires restart, keep %q", old.Port)
+		app.Port = old.Port
+	}
+
+	if app.ProfilerAddr != old.ProfilerAddr {
+		d.logger.Warnf("app.profiler_addr change requires restart, keep %q", old.ProfilerAddr)
+		app.ProfilerAddr = old.ProfilerAddr
+	}
+
+	if app.ErrorBuffer != old.ErrorBuffer {
+		d.logger.Warnf("app.error_buffer change requires restart, keep %d", old.ErrorBuffer)
+		app.ErrorBuffer = old.ErrorBuffer
+	}
+
+	if app.HTTP.ErrorBuffer != old.HTTP.ErrorBuffer {
+		d.logger.Warnf("app.http.error_buffer change requires restart, keep %d", old.HTTP.ErrorBuffer)
+		app.HTTP.ErrorBuffer = old.HTTP.ErrorBuffer
+	}
+
+	if !reflect.DeepEqual(app.Leader, old.Leader) {
+		d.logger.Warn("app.leader change requires restart, keep old settings")
+		app.Leader = old.Leader
+	}
+}
+
+// applyConfig applies changes of the current config compared to the old one.
+func (d *Daemon) applyConfig(old *Config) {
+	app := &d.config.App
+
+	if !reflect.DeepEqual(app.Log, old.App.Log) {
+		d.logger.Logger().Customize(&app.Log)
+		d.logger.Infof("app.log changed: level %q", app.Log.Level)
+	}
+
+	if app.CheckConnectionsInterval != old.App.CheckConnectionsInterval {
+		d.refresher.Reset(app.CheckConnectionsInterval)
+		d.logger.Infof("app.check_connections_interval changed: %s", app.CheckConnectionsInterval)
+	}
+
+	if app.Reload.WatchInterval != old.App.Reload.WatchInterval {
+		d.watchConfig()
+		d.logger.Infof("app.reload.watch_interval changed: %s", app.Reload.WatchInterval)
+	}
+
+	if !reflect.DeepEqual(app.Health.Required, old.App.Health.Required) {
+		d.health.setRequired(app.Health.Required)
+		d.logger.Infof("app.health.required changed: %v", app.Health.Required)
+	}
+
+	if app.Health.Interval != old.App.Health.Interval {
+		d.prober.Reset(app.Health.Interval)
+		d.logger.Infof("app.health.interval changed: %s", app.Health.Interval)
+	}
+
+	if !reflect.DeepEqual(app.Restart, old.App.Restart) {
+		// Retries budgets are recreated with the new backoff settings.
+		d.retriers = make(map[string]*retrier)
+		d.logger.Info("app.restart changed")
+	}
+
+	if !reflect.DeepEqual(app.HTTP, old.App.HTTP) {
+		// HTTP server settings are applied on start, so the server is
+		// restarted gracefully.
+		d.logger.Info("app.http changed")
+		d.restartComponent("http")
+	}
+}
+
+// watchConfig starts or stops the config files changes watching according to
+// app.reload.watch_interval.
+func (d *Daemon) watchConfig() {
+	if d.watcher != nil {
+		d.watcher.Stop()
+		d.watcher = nil
+		d.watcherC = nil
+	}
+
+	if d.config.App.Reload.WatchInterval == 0 {
+		return
+	}
+
+	d.watcher = time.NewTicker(d.config.App.Reload.WatchInterval)
+	d.watcherC = d.watcher.C
+
+	if modTimes, err := configModTimes(d.config.Files()); err == nil {
+		d.configModTimes = modTimes
+	}
+}
+
+// isConfigChanged checks modification time of the config files.
+func (d *Daemon) isConfigChanged() (bool, error) {
+	modTimes, err := configModTimes(d.config.Files())
+	if err != nil {
+		return false, err
+	}
+
+	if reflect.DeepEqual(modTimes, d.configModTimes) {
+		return false, nil
+	}
+
+	d.configModTimes = modTimes
+
+	return true, nil
+}
+
+// configModTimes returns modification times of the files.
+func configModTimes(names []string) ([]time.Time, error) {
+	modTimes := make([]time.Time, 0, len(names))
+
+	for _, name := range names {
+		info, err := os.Stat(name)
+		if err != nil {
+			return nil, fmt.Errorf("stat config: %w", err)
+		}
+
+		modTimes = append(modTimes, info.ModTime())
+	}
+
+	return modTimes, nil
+}
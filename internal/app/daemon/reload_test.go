@@ -0,0 +1,70 @@
+package daemon
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/outdead/goservice/internal/connector/fake"
+	"github.com/outdead/goservice/internal/utils/logutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDaemon_Reload(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "config.yaml")
+
+	write := func(data string) {
+		t.Helper()
+
+		if err := ioutil.WriteFile(name, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("app:\n  port: 8080\n  check_connections_interval: 1m\n  error_buffer: 10\n")
+
+	cfg, err := NewConfig(name)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	cfg.SetDefaults()
+
+	if !assert.NoError(t, cfg.Validate()) {
+		return
+	}
+
+	d := NewDaemon(cfg, logutil.NewDiscardLogger().NewEntry())
+	d.conn = fake.New()
+
+	defer d.cancel()
+
+	tests := []struct {
+		name     string
+		data     string
+		accepted bool
+	}{
+		{"invalid value", "app:\n  port: 8080\n  check_connections_interval: 1m\n  error_buffer: 10\n  errors:\n    default: retry\n", false},
+		{"unknown key", "app:\n  port: 8080\n  check_connections_interval: 1m\n  error_buffer: 10\n  erors: {}\n", false},
+		{"broken file", "app: [", false},
+		{"valid", "app:\n  port: 8080\n  check_connections_interval: 1m\n  error_buffer: 10\n  errors:\n    default: log\n", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old := d.config
+
+			write(tt.data)
+			d.reload()
+
+			if !tt.accepted {
+				assert.Same(t, old, d.config, "invalid config is applied")
+
+				return
+			}
+
+			assert.NotSame(t, old, d.config)
+			assert.Equal(t, ActionLog, d.config.App.Errors.Default)
+		})
+	}
+}
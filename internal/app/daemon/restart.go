@@ -0,0 +1,142 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/outdead/goservice/internal/utils/backoff"
+)
+
+// ErrComponentNotStarted is returned on restart of the component which is not
+// running.
+var ErrComponentNotStarted = errors.New("component is not started")
+
+// retrier keeps retries state of the error source.
+type retrier struct {
+	backoff *backoff.Backoff
+	pending bool
+	retried time.Time
+}
+
+// scheduleRetry sends source name to the retries channel after backoff delay.
+// If app.restart.attempts are exhausted the error is reported to the daemon
+// errors channel.
+func (d *Daemon) scheduleRetry(source string, err error) {
+	d.schedule(source, err, d.retries)
+}
+
+// scheduleRestart sends component name to the restarts channel after backoff
+// delay. Retries and restarts share app.restart budget.
+func (d *Daemon) scheduleRestart(name string, err error) {
+	d.schedule(name, err, d.restarts)
+}
+
+// schedule sends source name to the channel after backoff delay if the retry
+// budget of the source is not exhausted.
+func (d *Daemon) schedule(source string, err error, ch chan<- string) {
+	r, ok := d.retriers[source]
+	if !ok {
+		r = &retrier{backoff: backoff.New(&d.config.App.Restart.Backoff)}
+		d.retriers[source] = r
+	}
+
+	if r.pending {
+		// Source is already waiting for retry and the error is most likely
+		// caused by the same failure.
+		d.logger.WithError(err).Warnf("%s is waiting for retry", source)
+
+		return
+	}
+
+	// Source which has been working longer than the max backoff interval
+	// since the last retry is considered healthy and gets full budget.
+	if !r.retried.IsZero() && time.Since(r.retried) > r.backoff.MaxInterval() {
+		r.backoff.Reset()
+	}
+
+	attempts := d.config.App.Restart.Attempts
+	if r.backoff.Attempt() >= attempts {
+		d.logger.Errorf("%s retry attempts exceeded: %d", source, attempts)
+		d.reportError(err)
+
+		return
+	}
+
+	delay := r.backoff.Next()
+	r.pending = true
+
+	d.logger.WithError(err).Warnf("retry %s in %s (attempt %d/%d)", source, delay, r.backoff.Attempt(), attempts)
+
+	// Run loop does not read the channel after the shutdown, so the timer
+	// goroutine must not wait for it.
+	time.AfterFunc(delay, func() {
+		select {
+		case ch <- source:
+		case <-d.ctx.Done():
+		}
+	})
+}
+
+// retried marks the scheduled retry of the source as done.
+func (d *Daemon) retried(source string) {
+	if r, ok := d.retriers[source]; ok {
+		r.pending = false
+		r.retried = time.Now()
+	}
+}
+
+// retry repeats the connections check.
+func (d *Daemon) retry(source string) {
+	d.retried(source)
+
+	if source != SourceConnector {
+		d.reportError(fmt.Errorf("%s: %w", source, ErrRetryNotSupported))
+
+		return
+	}
+
+	d.checkConnections()
+}
+
+// resetRetries restores retry budget of the source after successful retry.
+func (d *Daemon) resetRetries(source string) {
+	if r, ok := d.retriers[source]; ok && !r.pending {
+		r.backoff.Reset()
+	}
+}
+
+// restartComponent stops and starts again running component by name.
+func (d *Daemon) restartComponent(name string) {
+	d.retried(name)
+
+	for i, rc := range d.started {
+		if rc.Name() != name {
+			continue
+		}
+
+		d.logger.Infof("restarting %s...", name)
+
+		rc.unwatch()
+
+		if err := d.stopComponent(rc.Component); err != nil {
+			d.logger.WithError(err).Warnf("stop %s before restart error", name)
+		}
+
+		// The component stays in the started list to be stopped on close even
+		// if restart fails.
+		d.started[i] = d.watchComponent(rc.Component)
+
+		if err := rc.Start(); err != nil {
+			d.handleError(name, &ComponentError{Component: name, Err: fmt.Errorf("restart: %w", err)})
+
+			return
+		}
+
+		d.logger.Infof("restart %s success", name)
+
+		return
+	}
+
+	d.reportError(&ComponentError{Component: name, Err: ErrComponentNotStarted})
+}
@@ -0,0 +1,102 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/outdead/goservice/internal/app/server/http/response"
+	"github.com/urfave/cli/v2"
+)
+
+// DefaultHealthcheckPath is the endpoint called by healthcheck command if
+// --path is not set. Liveness is used because container restart does not
+// help when dependencies are down, use --path /system/health/ready to probe
+// readiness.
+const DefaultHealthcheckPath = "/system/health/live"
+
+// Healthcheck errors.
+var (
+	ErrEmptyPort        = errors.New("app.port is empty")
+	ErrUnexpectedStatus = errors.New("unexpected status")
+)
+
+// healthcheckCommand returns command which checks health of the running
+// instance for container probes.
+func (a *App) healthcheckCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "healthcheck",
+		Usage: "Call health endpoint of the running instance on app.port, exit 1 if it is unhealthy",
+		Flags: []cli.Flag{
+			configFlag(),
+			&cli.StringFlag{
+				Name:  "host",
+				Usage: "Host of the running instance",
+				Value: "127.0.0.1",
+			},
+			&cli.StringFlag{
+				Name:  "path",
+				Usage: "Health endpoint path",
+				Value: DefaultHealthcheckPath,
+			},
+			&cli.DurationFlag{
+				Name:  "timeout",
+				Usage: "Request timeout",
+				Value: 3 * time.Second,
+			},
+		},
+		Action: a.healthcheckAction(),
+	}
+}
+
+// healthcheckAction prints short status and exits with code 1 if the instance
+// is unreachable or responses non-200 status.
+func (a *App) healthcheckAction() func(c *cli.Context) error {
+	return func(c *cli.Context) error {
+		cfg, err := a.loadConfig(c)
+		if err != nil {
+			return cli.Exit("unhealthy: "+err.Error(), 1)
+		}
+
+		if cfg.App.Port == "" {
+			return cli.Exit("unhealthy: "+ErrEmptyPort.Error(), 1)
+		}
+
+		url := "http://" + net.JoinHostPort(c.String("host"), cfg.App.Port) + c.String("path")
+
+		if err := healthcheck(url, c.Duration("timeout")); err != nil {
+			return cli.Exit("unhealthy: "+err.Error(), 1)
+		}
+
+		fmt.Fprintln(c.App.Writer, "healthy")
+
+		return nil
+	}
+}
+
+// healthcheck calls url and returns error with the reason if response status
+// is not 200.
+func healthcheck(url string, timeout time.Duration) error {
+	client := http.Client{Timeout: timeout}
+
+	resp, err := client.Get(url) //nolint:noctx // Request is limited by client timeout.
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var body response.Response
+	if err := json.NewDecoder(resp.Body).Decode(&body); err == nil && body.Message != "" {
+		return fmt.Errorf("%w %s: %s", ErrUnexpectedStatus, resp.Status, body.Message)
+	}
+
+	return fmt.Errorf("%w %s", ErrUnexpectedStatus, resp.Status)
+}
@@ -0,0 +1,72 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/outdead/goservice/internal/app/server/http/response"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthcheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    string
+	}{
+		{
+			name: "healthy",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			},
+		},
+		{
+			name: "unhealthy with message",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				c := echo.New().NewContext(r, w)
+				_ = c.JSON(http.StatusServiceUnavailable, response.Response{Message: "postgres: connection refused"})
+			},
+			want: "unexpected status 503 Service Unavailable: postgres: connection refused",
+		},
+		{
+			name: "unhealthy without message",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			},
+			want: "unexpected status 503 Service Unavailable",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(tt.handler)
+			defer srv.Close()
+
+			err := healthcheck(srv.URL+DefaultHealthcheckPath, time.Second)
+			if tt.want == "" {
+				assert.NoError(t, err)
+
+				return
+			}
+
+			assert.True(t, errors.Is(err, ErrUnexpectedStatus))
+			assert.EqualError(t, err, tt.want)
+		})
+	}
+}
+
+func TestHealthcheck_Unreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL + DefaultHealthcheckPath
+	srv.Close()
+
+	err := healthcheck(url, time.Second)
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, ErrUnexpectedStatus))
+}
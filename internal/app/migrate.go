@@ -0,0 +1,152 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/outdead/goservice/internal/connector"
+	"github.com/outdead/goservice/internal/migrations"
+	"github.com/outdead/goservice/internal/utils/migrate"
+	"github.com/urfave/cli/v2"
+)
+
+// ErrInvalidSteps is returned when number of migrations to revert is not
+// positive.
+var ErrInvalidSteps = errors.New("steps must be positive number")
+
+// migrateCommand returns command which manages embedded database migrations.
+func (a *App) migrateCommand() *cli.Command {
+	database := &cli.StringSliceFlag{
+		Name:  "database",
+		Usage: "Database to migrate: postgres or clickhouse, can be repeated (default: all configured)",
+	}
+
+	return &cli.Command{
+		Name:  "migrate",
+		Usage: "Embedded database migrations",
+		Subcommands: []*cli.Command{
+			{
+				Name:   "up",
+				Usage:  "Apply pending migrations",
+				Flags:  []cli.Flag{configFlag(), database},
+				Action: a.migrateAction(a.migrateUp),
+			},
+			{
+				Name:  "down",
+				Usage: "Revert applied migrations",
+				Flags: []cli.Flag{
+					configFlag(),
+					database,
+					&cli.IntFlag{
+						Name:  "steps",
+						Usage: "Number of migrations to revert",
+						Value: 1,
+					},
+				},
+				Action: a.migrateAction(a.migrateDown),
+			},
+			{
+				Name:   "status",
+				Usage:  "Print applied and pending migrations",
+				Flags:  []cli.Flag{configFlag(), database},
+				Action: a.migrateAction(a.migrateStatus),
+			},
+		},
+	}
+}
+
+// migrateAction connects to the configured databases and calls fn for each
+// of them.
+func (a *App) migrateAction(fn func(c *cli.Context, m *migrate.Migrator) error) func(c *cli.Context) error {
+	return func(c *cli.Context) error {
+		cfg, err := a.loadConfig(c)
+		if err != nil {
+			return err
+		}
+
+		if err := cfg.Validate(); err != nil {
+			return fmt.Errorf("validate config: %w", err)
+		}
+
+		databases := c.StringSlice("database")
+
+		// Only databases with migrations are connected.
+		conns, err := migrations.Connections(&cfg.Connections, databases...)
+		if err != nil {
+			return fmt.Errorf("migrate: %w", err)
+		}
+
+		conn, err := connector.New(conns, connector.SetLogger(a.logger.NewEntry()))
+		if err != nil {
+			return fmt.Errorf("connector: %w", err)
+		}
+
+		defer conn.Close()
+
+		migrators, err := migrations.NewMigrators(conn, databases...)
+		if err != nil {
+			return fmt.Errorf("migrate: %w", err)
+		}
+
+		for _, m := range migrators {
+			if err := fn(c, m); err != nil {
+				return fmt.Errorf("migrate: %w", err)
+			}
+		}
+
+		return nil
+	}
+}
+
+func (a *App) migrateUp(c *cli.Context, m *migrate.Migrator) error {
+	applied, err := m.Up(c.Context)
+	for _, migration := range applied {
+		fmt.Fprintf(c.App.Writer, "%s: applied %d_%s\n", m.Name(), migration.Version, migration.Name)
+	}
+
+	if err == nil && len(applied) == 0 {
+		fmt.Fprintf(c.App.Writer, "%s: no pending migrations\n", m.Name())
+	}
+
+	return err
+}
+
+func (a *App) migrateDown(c *cli.Context, m *migrate.Migrator) error {
+	steps := c.Int("steps")
+	if steps <= 0 {
+		return ErrInvalidSteps
+	}
+
+	reverted, err := m.Down(c.Context, steps)
+	for _, migration := range reverted {
+		fmt.Fprintf(c.App.Writer, "%s: reverted %d_%s\n", m.Name(), migration.Version, migration.Name)
+	}
+
+	if err == nil && len(reverted) == 0 {
+		fmt.Fprintf(c.App.Writer, "%s: no applied migrations\n", m.Name())
+	}
+
+	return err
+}
+
+func (a *App) migrateStatus(c *cli.Context, m *migrate.Migrator) error {
+	statuses, err := m.Status(c.Context)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(c.App.Writer, 0, 0, 2, ' ', 0)
+
+	for _, status := range statuses {
+		state := "pending"
+		if status.Applied {
+			state = "applied " + status.AppliedAt.Format(time.RFC3339)
+		}
+
+		fmt.Fprintf(w, "%s\t%d_%s\t%s\n", m.Name(), status.Version, status.Name, state)
+	}
+
+	return w.Flush()
+}
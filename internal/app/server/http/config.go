@@ -0,0 +1,64 @@
+package http
+
+import (
+	"errors"
+	"time"
+
+	"github.com/outdead/goservice/internal/utils/multierror"
+)
+
+// DefaultErrorBuffer is the size of the server errors channel if
+// error_buffer is not set.
+const DefaultErrorBuffer = 100
+
+// Config validation errors.
+var (
+	ErrInvalidReadTimeout  = errors.New("read_timeout must be positive number or zero")
+	ErrInvalidWriteTimeout = errors.New("write_timeout must be positive number or zero")
+	ErrInvalidIdleTimeout  = errors.New("idle_timeout must be positive number or zero")
+	ErrInvalidErrorBuffer  = errors.New("error_buffer must be positive number")
+)
+
+// Config contains HTTP server settings. Zero timeout means no timeout.
+type Config struct {
+	ReadTimeout  time.Duration `json:"read_timeout" yaml:"read_timeout"`
+	WriteTimeout time.Duration `json:"write_timeout" yaml:"write_timeout"`
+	IdleTimeout  time.Duration `json:"idle_timeout" yaml:"idle_timeout"`
+	// ErrorBuffer is the size of the server errors channel. It is applied
+	// on the server creation only.
+	ErrorBuffer int `json:"error_buffer" yaml:"error_buffer"`
+}
+
+// SetDefaults sets default values of the fields which are not set.
+func (cfg *Config) SetDefaults() {
+	if cfg.ErrorBuffer == 0 {
+		cfg.ErrorBuffer = DefaultErrorBuffer
+	}
+}
+
+// Validate checks required fields and validates for allowed values.
+func (cfg *Config) Validate() error {
+	errs := multierror.New()
+
+	if cfg.ReadTimeout < 0 {
+		errs.Append(multierror.Field("read_timeout", ErrInvalidReadTimeout))
+	}
+
+	if cfg.WriteTimeout < 0 {
+		errs.Append(multierror.Field("write_timeout", ErrInvalidWriteTimeout))
+	}
+
+	if cfg.IdleTimeout < 0 {
+		errs.Append(multierror.Field("idle_timeout", ErrInvalidIdleTimeout))
+	}
+
+	if cfg.ErrorBuffer <= 0 {
+		errs.Append(multierror.Field("error_buffer", ErrInvalidErrorBuffer))
+	}
+
+	if errs.Len() != 0 {
+		return errs
+	}
+
+	return nil
+}
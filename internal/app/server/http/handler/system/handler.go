@@ -3,14 +3,21 @@ package system
 import (
 	"github.com/labstack/echo/v4"
 	"github.com/outdead/goservice/internal/app/server/http/response"
+	"github.com/outdead/goservice/internal/utils/errclass"
 )
 
 // Handler is wrapper for HTTP API handle functions in health group.
-type Handler struct{}
+type Handler struct {
+	errorCounter *errclass.Counter
+	isDraining   func() bool
+	dependencies DependenciesFunc
+}
 
-// NewHandler creates new Handler.
-func NewHandler() *Handler {
-	return &Handler{}
+// NewHandler creates new Handler. isDraining reports whether the service is
+// shutting down and must not receive new requests. dependencies returns
+// status of the service dependencies for the readiness probe.
+func NewHandler(errorCounter *errclass.Counter, isDraining func() bool, dependencies DependenciesFunc) *Handler {
+	return &Handler{errorCounter: errorCounter, isDraining: isDraining, dependencies: dependencies}
 }
 
 // Ping godoc
@@ -27,3 +34,60 @@ func NewHandler() *Handler {
 func (h *Handler) Ping(c echo.Context) error {
 	return response.ServeResult(c, "pong")
 }
+
+// Errors godoc
+// @Summary Errors stats
+// @Description Get counters of daemon errors by classes and sources
+// @Tags system
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} response.Response{result=errclass.Stats}
+// @Failure 200 {object} response.Response
+// @Router /system/errors [get]
+//
+// Errors responses counters of errors received by daemon.
+func (h *Handler) Errors(c echo.Context) error {
+	return response.ServeResult(c, h.errorCounter.Stats())
+}
+
+// Live godoc
+// @Summary Liveness probe
+// @Description Check the service process is alive
+// @Tags system
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} response.Response
+// @Router /system/health/live [get]
+//
+// Live responses 200 status while the process is able to serve requests.
+// Dependencies are not checked, so their outage does not restart the service.
+func (h *Handler) Live(c echo.Context) error {
+	return response.ServeResult(c, "alive")
+}
+
+// Ready godoc
+// @Summary Readiness probe
+// @Description Check the service is ready to receive requests
+// @Tags system
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} response.Response{result=system.Health}
+// @Failure 503 {object} response.Response{result=system.Health}
+// @Router /system/health/ready [get]
+//
+// Ready responses 503 status while the service is draining before shutdown
+// or any of the required dependencies is unavailable. Status of each
+// dependency is served in the result. Dependencies are not checked by the
+// probe, the last periodic check is served.
+func (h *Handler) Ready(c echo.Context) error {
+	health := h.health()
+
+	switch {
+	case health.Draining:
+		return response.ServeServiceUnavailableResult(c, health, "service is draining")
+	case !health.Ready:
+		return response.ServeServiceUnavailableResult(c, health, "required dependency is unavailable")
+	}
+
+	return response.ServeResult(c, health)
+}
@@ -0,0 +1,51 @@
+package system
+
+import "time"
+
+// Dependency contains health status of the service dependency.
+type Dependency struct {
+	Name string `json:"name"`
+	OK   bool   `json:"ok"`
+	// Required dependencies fail the readiness probe when they are not OK.
+	Required bool `json:"required"`
+	// Latency is the duration of the check, e.g. "1.5ms".
+	Latency string `json:"latency"`
+	Error   string `json:"error,omitempty"`
+	// CheckedAt is the time of the check. Probes are served from the last
+	// periodic check, so the status can be as old as the check interval.
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Health contains readiness status of the service.
+type Health struct {
+	Ready        bool         `json:"ready"`
+	Draining     bool         `json:"draining"`
+	Dependencies []Dependency `json:"dependencies"`
+}
+
+// DependenciesFunc returns the last known status of the service
+// dependencies. It is called on every readiness probe and must not check
+// dependencies itself.
+type DependenciesFunc func() []Dependency
+
+// health reports whether the service is ready.
+func (h *Handler) health() Health {
+	health := Health{
+		Draining:     h.isDraining(),
+		Dependencies: h.dependencies(),
+	}
+
+	if health.Dependencies == nil {
+		health.Dependencies = []Dependency{}
+	}
+
+	health.Ready = !health.Draining
+
+	for _, dep := range health.Dependencies {
+		if dep.Required && !dep.OK {
+			health.Ready = false
+		}
+	}
+
+	return health
+}
@@ -78,6 +78,24 @@ func ServeInternalServerError(c echo.Context, msg ...string) error {
 	return Serve(c, http.StatusInternalServerError, msg...)
 }
 
+// ServeServiceUnavailableResult sends a JSON response with a 503 code, the
+// passed result and error message. Unlike other errors the response is sent
+// with 503 HTTP status too because load balancers and orchestrators check the
+// status code only. It is used by probes which explain the reason of
+// unavailability in the result.
+func ServeServiceUnavailableResult(c echo.Context, result interface{}, msg ...string) error {
+	message := http.StatusText(http.StatusServiceUnavailable)
+	if len(msg) != 0 {
+		message = msg[0]
+	}
+
+	return c.JSON(http.StatusServiceUnavailable, Response{
+		Code:    http.StatusServiceUnavailable,
+		Message: message,
+		Result:  result,
+	})
+}
+
 // Serve sends a JSON response with the passed code and error message.
 // It is possible not to pass an error message - in this case it will be taken
 // based on the response code.
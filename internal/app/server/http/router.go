@@ -19,8 +19,11 @@ import (
 func (s *Server) router() {
 	root := s.echo.Group("")
 
-	systemHandler := system.NewHandler()
+	systemHandler := system.NewHandler(s.errorCounter, s.isDraining, s.dependencies)
 	root.GET("/system/ping", systemHandler.Ping)
+	root.GET("/system/errors", systemHandler.Errors)
+	root.GET("/system/health/live", systemHandler.Live)
+	root.GET("/system/health/ready", systemHandler.Ready)
 
 	root.GET("/swagger/*", swagger.WrapHandler)
 }
@@ -9,12 +9,14 @@ import (
 	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/outdead/goservice/internal/app/server/http/handler/system"
 	"github.com/outdead/goservice/internal/app/server/http/middleware"
 	"github.com/outdead/goservice/internal/app/server/http/response"
+	"github.com/outdead/goservice/internal/utils/errclass"
 	"github.com/outdead/goservice/internal/utils/logutil"
 )
 
-// ShutdownTimeOut is time to terminate queries when quit signal given.
+// ShutdownTimeOut is default time to terminate queries when quit signal given.
 const ShutdownTimeOut = 10 * time.Second
 
 // ErrLockedServer returned on repeated call Close() the HTTP server.
@@ -22,23 +24,97 @@ var ErrLockedServer = errors.New("http api server is locked")
 
 // Server defines parameters for running an HTTP server.
 type Server struct {
+	config *Config
 	logger *logutil.Entry
 	errors chan error
 	quit   chan bool
 	wg     sync.WaitGroup
 
 	echo *echo.Echo
+
+	errorCounter    *errclass.Counter
+	isDraining      func() bool
+	dependencies    system.DependenciesFunc
+	shutdownTimeout time.Duration
+}
+
+// Option allows to inject options to Server.
+type Option func(s *Server)
+
+// SetConfig injects HTTP server settings.
+func SetConfig(cfg *Config) Option {
+	return func(s *Server) {
+		s.SetConfig(cfg)
+	}
+}
+
+// SetErrorCounter injects daemon errors counter which stats are served by
+// the system handler.
+func SetErrorCounter(counter *errclass.Counter) Option {
+	return func(s *Server) {
+		s.errorCounter = counter
+	}
+}
+
+// SetDraining injects function which reports whether the service is shutting
+// down. Readiness probe fails while it returns true.
+func SetDraining(isDraining func() bool) Option {
+	return func(s *Server) {
+		s.isDraining = isDraining
+	}
+}
+
+// SetDependencies injects function which returns status of the service
+// dependencies for the readiness probe.
+func SetDependencies(dependencies system.DependenciesFunc) Option {
+	return func(s *Server) {
+		s.dependencies = dependencies
+	}
+}
+
+// SetShutdownTimeout injects time to wait for in-flight requests on Close.
+func SetShutdownTimeout(timeout time.Duration) Option {
+	return func(s *Server) {
+		s.shutdownTimeout = timeout
+	}
 }
 
 // NewServer allocates and returns a new Server.
-func NewServer(log *logutil.Entry) *Server {
+func NewServer(log *logutil.Entry, options ...Option) *Server {
 	s := Server{
+		config: new(Config),
 		logger: log,
-		errors: make(chan error, 100),
 		quit:   make(chan bool),
 		echo:   echo.New(),
 	}
 
+	for _, option := range options {
+		option(&s)
+	}
+
+	buffer := s.config.ErrorBuffer
+	if buffer == 0 {
+		buffer = DefaultErrorBuffer
+	}
+
+	s.errors = make(chan error, buffer)
+
+	if s.errorCounter == nil {
+		s.errorCounter = errclass.NewCounter()
+	}
+
+	if s.isDraining == nil {
+		s.isDraining = func() bool { return false }
+	}
+
+	if s.dependencies == nil {
+		s.dependencies = func() []system.Dependency { return nil }
+	}
+
+	if s.shutdownTimeout == 0 {
+		s.shutdownTimeout = ShutdownTimeOut
+	}
+
 	return &s
 }
 
@@ -47,12 +123,14 @@ func (s *Server) Serve(port string) {
 	s.echo = s.newEcho()
 	s.router()
 
-	go func() {
-		if err := s.echo.Start(":" + port); err != nil && !errors.Is(err, http.ErrServerClosed) {
+	// Echo instance is recreated on each Serve call, so the goroutine gets
+	// its own copy to not race with the restart.
+	go func(e *echo.Echo) {
+		if err := e.Start(":" + port); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			// Report error if server is not closed by Echo#Shutdown.
 			s.reportError(fmt.Errorf("start http server error: %w", err))
 		}
-	}()
+	}(s.echo)
 
 	s.logger.Infof("http server started on port %s", port)
 
@@ -65,7 +143,7 @@ func (s *Server) Serve(port string) {
 		<-s.quit
 		s.logger.Debug("stopping http api server...")
 
-		ctx, cancel := context.WithTimeout(context.Background(), ShutdownTimeOut)
+		ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
 		defer cancel()
 
 		if s.echo != nil {
@@ -76,6 +154,12 @@ func (s *Server) Serve(port string) {
 	}()
 }
 
+// SetConfig changes HTTP server settings. New settings are applied on the next
+// Serve call.
+func (s *Server) SetConfig(cfg *Config) {
+	s.config = cfg
+}
+
 // Close stops HTTP Server.
 func (s *Server) Close() error {
 	if s.quit == nil {
@@ -106,6 +190,10 @@ func (s *Server) newEcho() *echo.Echo {
 	e.HideBanner = true
 	e.HidePort = true
 
+	e.Server.ReadTimeout = s.config.ReadTimeout
+	e.Server.WriteTimeout = s.config.WriteTimeout
+	e.Server.IdleTimeout = s.config.IdleTimeout
+
 	e.HTTPErrorHandler = s.httpErrorHandler
 
 	return e
@@ -114,25 +202,27 @@ func (s *Server) newEcho() *echo.Echo {
 // httpErrorHandler customizes error response.
 // @source: https://github.com/labstack/echo/issues/325
 func (s *Server) httpErrorHandler(err error, c echo.Context) {
+	// Errors of responses writing are caused by the client connection and do
+	// not affect the server.
 	var t *echo.HTTPError
 	if errors.As(err, &t) {
 		switch t.Code {
 		case http.StatusNotFound, http.StatusMethodNotAllowed:
 			if err := response.ServeNotFoundError(c); err != nil {
-				s.reportError(err)
+				s.reportError(errclass.Wrap(err, errclass.Transient))
 			}
 		default:
 			s.logger.WithField("url", c.Path()).Errorf("unexpected http code: %d", t.Code)
 
 			if err := response.ServeInternalServerError(c); err != nil {
-				s.reportError(err)
+				s.reportError(errclass.Wrap(err, errclass.Transient))
 			}
 		}
 	} else {
 		s.logger.WithField("url", c.Path()).Errorf("unexpected http error: %s", err)
 
 		if err := response.ServeInternalServerError(c); err != nil {
-			s.reportError(err)
+			s.reportError(errclass.Wrap(err, errclass.Transient))
 		}
 	}
 }
@@ -1,6 +1,8 @@
 package profiler
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 
 	// Load the dependency to enable the profiler.
@@ -9,14 +11,73 @@ import (
 	"github.com/outdead/goservice/internal/utils/logutil"
 )
 
-// Serve starts an HTTP server on the given port and allows you to profile the
-// service by reference {host}:{port}/debug/pprof/.
-func Serve(addr string, log *logutil.Entry) {
-	if addr != "" {
-		go func() {
-			log.Error(http.ListenAndServe(addr, nil))
-		}()
+// ErrLockedServer returned on repeated call Close() the profiler server.
+var ErrLockedServer = errors.New("profiler server is locked")
 
-		log.Infof("profiler started on %s", addr)
+// Server is an HTTP server that allows you to profile the service by reference
+// {host}:{port}/debug/pprof/.
+type Server struct {
+	logger *logutil.Entry
+	errors chan error
+
+	server *http.Server
+}
+
+// NewServer allocates and returns a new Server.
+func NewServer(log *logutil.Entry) *Server {
+	s := Server{
+		logger: log,
+		errors: make(chan error, 1),
+	}
+
+	return &s
+}
+
+// Serve starts an HTTP server on the given address in a separate goroutine.
+func (s *Server) Serve(addr string) {
+	// Profiler handlers are registered by net/http/pprof in default mux.
+	s.server = &http.Server{Addr: addr, Handler: http.DefaultServeMux}
+
+	go func(server *http.Server) {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.reportError(fmt.Errorf("start profiler error: %w", err))
+		}
+	}(s.server)
+
+	s.logger.Infof("profiler started on %s", addr)
+}
+
+// Close stops profiler server.
+func (s *Server) Close() error {
+	if s.server == nil {
+		return ErrLockedServer
+	}
+
+	// Profiling requests can last for a long time (see the seconds parameter
+	// of /debug/pprof/profile), so do not wait for them and close immediately.
+	if err := s.server.Close(); err != nil {
+		return fmt.Errorf("shutdown profiler error: %w", err)
+	}
+
+	s.server = nil
+	s.logger.Info("stop profiler success")
+
+	return nil
+}
+
+// Errors returns errors channel.
+func (s *Server) Errors() <-chan error {
+	return s.errors
+}
+
+func (s *Server) reportError(err error) {
+	if err != nil {
+		select {
+		case s.errors <- err:
+		default:
+			// Profiler is not critical for the service so the error is only
+			// logged if no one reads the errors channel.
+			s.logger.Errorf("profiler error channel is locked: %s", err)
+		}
 	}
 }
@@ -0,0 +1,41 @@
+package app
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/urfave/cli/v2"
+)
+
+// unknown is printed instead of build metadata which is not set.
+const unknown = "unknown"
+
+// versionCommand returns command which prints build metadata.
+func (a *App) versionCommand() *cli.Command {
+	return &cli.Command{
+		Name:   "version",
+		Usage:  "Print version and build metadata",
+		Action: a.versionAction(),
+	}
+}
+
+func (a *App) versionAction() func(c *cli.Context) error {
+	return func(c *cli.Context) error {
+		commit, buildDate := a.commit, a.buildDate
+
+		if commit == "" {
+			commit = unknown
+		}
+
+		if buildDate == "" {
+			buildDate = unknown
+		}
+
+		fmt.Fprintf(c.App.Writer, "%s %s\n", a.name, a.version)
+		fmt.Fprintf(c.App.Writer, "commit: %s\n", commit)
+		fmt.Fprintf(c.App.Writer, "built: %s\n", buildDate)
+		fmt.Fprintf(c.App.Writer, "go: %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+
+		return nil
+	}
+}
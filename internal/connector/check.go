@@ -0,0 +1,212 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/outdead/goservice/internal/utils/backoff"
+	"github.com/outdead/goservice/internal/utils/multierror"
+)
+
+// DefaultCheckTimeout is the time given to connection checks if timeout is
+// not set.
+const DefaultCheckTimeout = 5 * time.Second
+
+// ErrCheckTimeout is reported for the connection which check has not finished
+// in time.
+var ErrCheckTimeout = errors.New("connection check timeout")
+
+// Status contains result of the connection check.
+type Status struct {
+	Name    string
+	OK      bool
+	Latency time.Duration
+	Err     error
+}
+
+// Report contains statuses of the checked connections in the fixed order:
+// postgres, clickhouse, elasticsearch, redis, rabbitmq. Named instances
+// follow the default instance of the driver sorted by names.
+type Report []Status
+
+// OK reports whether all checked connections are available.
+func (r Report) OK() bool {
+	for i := range r {
+		if !r[i].OK {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Err returns multierror with errors of the failed checks or nil.
+func (r Report) Err() error {
+	errs := multierror.New()
+
+	for i := range r {
+		errs.Append(r[i].Err)
+	}
+
+	if errs.Len() != 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// check describes connection check.
+type check struct {
+	name        string
+	isConnected func(ctx context.Context) bool
+	errLost     error
+}
+
+// checks returns checks of the established connections in order of the
+// config sections.
+func (conn *connector) checks() []check {
+	conn.mu.RLock()
+	defer conn.mu.RUnlock()
+
+	var checks []check
+
+	for _, s := range conn.config.sections() {
+		inst, ok := conn.instances[s.id]
+		if !ok {
+			continue
+		}
+
+		checks = append(checks, check{
+			name:        s.id,
+			isConnected: func(ctx context.Context) bool { return inst.driver.IsConnected(ctx, inst.conn) },
+			errLost:     instanceError(s.name, s.driver.ErrLost),
+		})
+	}
+
+	return checks
+}
+
+// CheckConnections checks established connections concurrently and returns
+// report with status of each of them. Checks which have not finished in
+// timeout are reported as failed with ErrCheckTimeout. Lost connections are
+// reconnected in background if reconnect is enabled. Returned error is
+// multierror of the connections lost longer than reconnect.outage_window or
+// nil. Such outages are not expected to disappear on their own, so the error
+// is recoverable. Zero timeout means DefaultCheckTimeout.
+func (conn *connector) CheckConnections(timeout time.Duration) (Report, error) {
+	if timeout <= 0 {
+		timeout = DefaultCheckTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return conn.CheckConnectionsContext(ctx)
+}
+
+// CheckConnectionsContext checks connections as CheckConnections does until
+// ctx deadline or DefaultCheckTimeout if ctx has no deadline. Checks are
+// aborted when ctx is done. If ctx is canceled, e.g. by the client of the
+// HTTP request, unfinished checks are reported with ctx error, outages are
+// not tracked and the ctx error is returned.
+func (conn *connector) CheckConnectionsContext(ctx context.Context) (Report, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, DefaultCheckTimeout)
+		defer cancel()
+	}
+
+	type result struct {
+		i       int
+		ok      bool
+		latency time.Duration
+	}
+
+	checks := conn.checks()
+	report := make(Report, len(checks))
+	// Results channel is buffered so checks finished after ctx is done do
+	// not block.
+	results := make(chan result, len(checks))
+	start := time.Now()
+
+	for i := range checks {
+		report[i] = Status{Name: checks[i].name}
+
+		go func(i int) {
+			start := time.Now()
+			ok := checks[i].isConnected(ctx)
+			results <- result{i: i, ok: ok, latency: time.Since(start)}
+		}(i)
+	}
+
+	done := make([]bool, len(checks))
+
+Loop:
+	for pending := len(checks); pending > 0; pending-- {
+		select {
+		case r := <-results:
+			done[r.i] = true
+			status := &report[r.i]
+			status.OK, status.Latency = r.ok, r.latency
+
+			if !r.ok {
+				status.Err = checks[r.i].errLost
+			}
+		case <-ctx.Done():
+			break Loop
+		}
+	}
+
+	for i := range report {
+		if done[i] {
+			continue
+		}
+
+		err := ErrCheckTimeout
+		if errors.Is(ctx.Err(), context.Canceled) {
+			err = ctx.Err()
+		}
+
+		report[i].Latency = time.Since(start)
+		report[i].Err = fmt.Errorf("%s: %w", report[i].Name, err)
+	}
+
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return report, ctx.Err()
+	}
+
+	return report, conn.handleOutages(report)
+}
+
+// Check connects to every configured database one by one and closes the
+// connections. Latency includes connection establishing and the first ping.
+// Absent and disabled connections are skipped. Startup retries are not
+// applied, so unavailable database is reported at once.
+func Check(cfg *Config, options ...Option) Report {
+	conn := newConnector(cfg, options...)
+	defer conn.cancel()
+
+	report := make(Report, 0)
+
+	for _, s := range cfg.sections() {
+		if !s.config.IsEnabled() {
+			continue
+		}
+
+		start := time.Now()
+		inst, err := conn.dial(s, new(backoff.RetryConfig))
+		status := Status{Name: s.id, OK: err == nil, Latency: time.Since(start), Err: err}
+
+		if err == nil {
+			// Close error does not affect availability of the database.
+			_ = inst.Close()
+		}
+
+		report = append(report, status)
+	}
+
+	return report
+}
@@ -0,0 +1,218 @@
+package connector_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/outdead/goservice/internal/connector"
+	"github.com/outdead/goservice/internal/utils/backoff"
+	"github.com/outdead/goservice/internal/utils/multierror"
+	"github.com/stretchr/testify/assert"
+)
+
+var errCheck = errors.New("check error")
+
+func TestReport(t *testing.T) {
+	tests := []struct {
+		name   string
+		report connector.Report
+		ok     bool
+		errs   int
+	}{
+		{"empty", connector.Report{}, true, 0},
+		{"all ok", connector.Report{{Name: alpha, OK: true}, {Name: beta, OK: true}}, true, 0},
+		{"one failed", connector.Report{{Name: alpha, OK: true}, {Name: beta, Err: errCheck}}, false, 1},
+		{"all failed", connector.Report{{Name: alpha, Err: errCheck}, {Name: beta, Err: errCheck}}, false, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.ok, tt.report.OK())
+
+			err := tt.report.Err()
+			if tt.errs == 0 {
+				assert.NoError(t, err)
+
+				return
+			}
+
+			var merr multierror.Error
+			if assert.True(t, errors.As(err, &merr)) {
+				assert.Equal(t, tt.errs, merr.Len())
+			}
+		})
+	}
+}
+
+func TestConnector_CheckConnections(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(a, b *Server)
+		// errs contains expected errors of alpha and beta checks.
+		errs []error
+	}{
+		{
+			name:  "available",
+			setup: func(a, b *Server) {},
+			errs:  []error{nil, nil},
+		},
+		{
+			name:  "slow check",
+			setup: func(a, b *Server) { a.SetLatency(time.Second) },
+			errs:  []error{connector.ErrCheckTimeout, nil},
+		},
+		{
+			name:  "failed check",
+			setup: func(a, b *Server) { b.SetDown(true) },
+			errs:  []error{nil, connector.ErrLostConnection},
+		},
+		{
+			name:  "slow and failed checks",
+			setup: func(a, b *Server) { a.SetLatency(time.Second); b.SetDown(true) },
+			errs:  []error{connector.ErrCheckTimeout, connector.ErrLostConnection},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addrA, a := NewServer()
+			addrB, b := NewServer()
+
+			conn, err := connector.New(&connector.Config{Drivers: connector.DriverConfigs{
+				alpha: &TestConfig{Addr: addrA},
+				beta:  &TestConfig{Addr: addrB},
+			}})
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			defer conn.Close()
+
+			tt.setup(a, b)
+
+			report, err := conn.CheckConnections(100 * time.Millisecond)
+
+			if assert.Len(t, report, 2) {
+				for i, name := range []string{alpha, beta} {
+					assert.Equal(t, name, report[i].Name)
+					assert.Equal(t, tt.errs[i] == nil, report[i].OK, name)
+					assert.True(t, errors.Is(report[i].Err, tt.errs[i]), "%s: expected %v, got %v", name, tt.errs[i], report[i].Err)
+				}
+			}
+
+			// Zero outage window returns errors of all failed checks.
+			var merr multierror.Error
+
+			failed := 0
+			for _, e := range tt.errs {
+				if e != nil {
+					failed++
+				}
+			}
+
+			if failed == 0 {
+				assert.NoError(t, err)
+			} else if assert.True(t, errors.As(err, &merr)) {
+				assert.Equal(t, failed, merr.Len())
+			}
+		})
+	}
+}
+
+func TestConnector_CheckConnectionsContext_Canceled(t *testing.T) {
+	addr, s := NewServer()
+
+	conn, err := connector.New(&connector.Config{Drivers: connector.DriverConfigs{alpha: &TestConfig{Addr: addr}}})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	defer conn.Close()
+
+	s.SetLatency(time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	report, err := conn.CheckConnectionsContext(ctx)
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	if assert.Len(t, report, 1) {
+		assert.True(t, errors.Is(report[0].Err, context.Canceled))
+	}
+}
+
+func TestConnector_CheckConnections_OutageWindow(t *testing.T) {
+	addr, s := NewServer()
+
+	cfg := connector.Config{Drivers: connector.DriverConfigs{alpha: &TestConfig{Addr: addr}}}
+	cfg.Reconnect.OutageWindow = time.Hour
+
+	conn, err := connector.New(&cfg)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	defer conn.Close()
+
+	s.SetDown(true)
+
+	// Outage shorter than the window is reported but is not returned.
+	report, err := conn.CheckConnections(time.Second)
+	assert.NoError(t, err)
+	assert.False(t, report.OK())
+}
+
+func TestCheck(t *testing.T) {
+	addrA, _ := NewServer()
+	addrB, b := NewServer()
+
+	b.SetRefuse(true)
+
+	startup := backoff.RetryConfig{MaxWait: time.Minute, Backoff: backoff.Config{InitialInterval: time.Second}}
+	startup.SetDefaults()
+
+	cfg := connector.Config{Drivers: connector.DriverConfigs{
+		alpha: &TestConfig{Addr: addrA, Startup: startup},
+		beta:  &TestConfig{Addr: addrB, Startup: startup},
+	}}
+
+	start := time.Now()
+
+	// Startup retries are not applied, unavailable database is reported at
+	// once.
+	report := connector.Check(&cfg)
+
+	assert.Less(t, int64(time.Since(start)), int64(startup.Backoff.InitialInterval))
+
+	if assert.Len(t, report, 2) {
+		assert.True(t, report[0].OK)
+		assert.False(t, report[1].OK)
+		assert.ErrorIs(t, report[1].Err, errRefused)
+	}
+}
+
+func TestNew_Canceled(t *testing.T) {
+	addr, s := NewServer()
+
+	s.SetRefuse(true)
+
+	startup := backoff.RetryConfig{MaxWait: time.Minute, Backoff: backoff.Config{InitialInterval: time.Second}}
+	startup.SetDefaults()
+
+	cfg := connector.Config{Drivers: connector.DriverConfigs{alpha: &TestConfig{Addr: addr, Startup: startup}}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+
+	// Startup retries are stopped by the parent context.
+	_, err := connector.New(&cfg, connector.SetContext(ctx))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), context.Canceled.Error())
+	}
+	assert.Less(t, int64(time.Since(start)), int64(startup.Backoff.InitialInterval))
+}
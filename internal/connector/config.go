@@ -1,45 +1,479 @@
 package connector
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"time"
 
+	"github.com/outdead/goservice/internal/utils/backoff"
 	"github.com/outdead/goservice/internal/utils/driver/clickhouse"
 	"github.com/outdead/goservice/internal/utils/driver/elasticsearch"
 	"github.com/outdead/goservice/internal/utils/driver/postgres"
 	"github.com/outdead/goservice/internal/utils/driver/rabbit"
 	"github.com/outdead/goservice/internal/utils/driver/redis"
+	"github.com/outdead/goservice/internal/utils/multierror"
+	"gopkg.in/yaml.v3"
 )
 
-// Config contains credentials for databases.
+// DefaultInstance is the name of the connection configured in the driver
+// section, e.g. connections.postgres. Accessors called without name return
+// the default instance.
+const DefaultInstance = "default"
+
+// DefaultCloseDelay is the time replaced connections are kept open if
+// reconnect.close_delay is not set.
+const DefaultCloseDelay = 30 * time.Second
+
+// Config validation errors.
+var (
+	ErrInvalidOutageWindow = errors.New("outage_window must be positive number or zero")
+	ErrInvalidCloseDelay   = errors.New("close_delay must be positive number or zero")
+	ErrInvalidInstanceName = errors.New("instance name must contain lowercase letters, digits and underscores and must not be default")
+)
+
+// instanceName matches allowed names of the named instances.
+var instanceName = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// Config contains credentials for databases. Each connection is optional:
+// absent section or section with `disabled: true` is not connected. Driver
+// sections configure the default instances, additional instances are
+// configured in instances section by names, e.g.
+//
+//	connections:
+//	  postgres: {...}
+//	  instances:
+//	    postgres:
+//	      analytics: {...}
+//
+// Fields of the sections are not required by the config schema because
+// sections can be disabled or split between several files. They are checked
+// by Validate of the merged config.
 type Config struct {
-	Postgres      postgres.Config      `yaml:"postgres" json:"postgres"`
-	Clickhouse    clickhouse.Config    `yaml:"clickhouse" json:"clickhouse"`
-	Elasticsearch elasticsearch.Config `yaml:"elasticsearch" json:"elasticsearch"`
-	Redis         redis.Config         `yaml:"redis" json:"redis"`
-	RabbitMQ      rabbit.Config        `yaml:"rabbitmq" json:"rabbit_mq"`
+	Postgres      *postgres.Config      `yaml:"postgres,omitempty" json:"postgres,omitempty"`
+	Clickhouse    *clickhouse.Config    `yaml:"clickhouse,omitempty" json:"clickhouse,omitempty"`
+	Elasticsearch *elasticsearch.Config `yaml:"elasticsearch,omitempty" json:"elasticsearch,omitempty"`
+	Redis         *redis.Config         `yaml:"redis,omitempty" json:"redis,omitempty"`
+	RabbitMQ      *rabbit.Config        `yaml:"rabbitmq,omitempty" json:"rabbit_mq,omitempty"`
+
+	// Drivers contains sections of the drivers registered by Register, e.g.
+	// connections.drivers.mongodb.
+	Drivers   DriverConfigs   `yaml:"drivers,omitempty" json:"drivers,omitempty"`
+	Instances InstancesConfig `yaml:"instances,omitempty" json:"instances,omitempty"`
+	Reconnect ReconnectConfig `yaml:"reconnect" json:"reconnect"`
+}
+
+// InstancesConfig contains named instances of the drivers. Instance name is
+// used in accessors, e.g. PG("analytics") or Conn("mongodb", "analytics"),
+// and is joined with the driver name by dot in the checks report, e.g.
+// postgres.analytics.
+type InstancesConfig struct {
+	Postgres      map[string]*postgres.Config      `yaml:"postgres,omitempty" json:"postgres,omitempty"`
+	Clickhouse    map[string]*clickhouse.Config    `yaml:"clickhouse,omitempty" json:"clickhouse,omitempty"`
+	Elasticsearch map[string]*elasticsearch.Config `yaml:"elasticsearch,omitempty" json:"elasticsearch,omitempty"`
+	Redis         map[string]*redis.Config         `yaml:"redis,omitempty" json:"redis,omitempty"`
+	RabbitMQ      map[string]*rabbit.Config        `yaml:"rabbitmq,omitempty" json:"rabbit_mq,omitempty"`
+
+	// Drivers contains named instances of the registered drivers, e.g.
+	// connections.instances.drivers.mongodb.analytics.
+	Drivers DriverInstances `yaml:"drivers,omitempty" json:"drivers,omitempty"`
+}
+
+// DriverConfigs contains configs of the registered drivers by driver names.
+// Sections are decoded to configs created by Driver.NewConfig, so drivers
+// must be registered before the config is decoded.
+type DriverConfigs map[string]DriverConfig
+
+// UnmarshalYAML decodes driver sections by yaml tags of the driver configs.
+func (configs *DriverConfigs) UnmarshalYAML(node *yaml.Node) error {
+	var sections map[string]yaml.Node
+	if err := node.Decode(&sections); err != nil {
+		return err
+	}
+
+	res := make(DriverConfigs, len(sections))
+
+	for name, section := range sections {
+		c, err := newDriverConfig(name)
+		if err != nil {
+			return err
+		}
+
+		if err := section.Decode(c); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+
+		res[name] = c
+	}
+
+	*configs = res
+
+	return nil
+}
+
+// UnmarshalJSON decodes driver sections by json tags of the driver configs.
+func (configs *DriverConfigs) UnmarshalJSON(data []byte) error {
+	var sections map[string]json.RawMessage
+	if err := json.Unmarshal(data, &sections); err != nil {
+		return err
+	}
+
+	res := make(DriverConfigs, len(sections))
+
+	for name, section := range sections {
+		c, err := newDriverConfig(name)
+		if err != nil {
+			return err
+		}
+
+		if err := json.Unmarshal(section, c); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+
+		res[name] = c
+	}
+
+	*configs = res
+
+	return nil
+}
+
+// DriverInstances contains named instances of the registered drivers by
+// driver names and instance names. Sections are decoded as DriverConfigs
+// ones.
+type DriverInstances map[string]map[string]DriverConfig
+
+// UnmarshalYAML decodes instance sections by yaml tags of the driver configs.
+func (instances *DriverInstances) UnmarshalYAML(node *yaml.Node) error {
+	var drivers map[string]map[string]yaml.Node
+	if err := node.Decode(&drivers); err != nil {
+		return err
+	}
+
+	res := make(DriverInstances, len(drivers))
+
+	for driver, sections := range drivers {
+		res[driver] = make(map[string]DriverConfig, len(sections))
+
+		for name, section := range sections {
+			c, err := newDriverConfig(driver)
+			if err != nil {
+				return err
+			}
+
+			if err := section.Decode(c); err != nil {
+				return fmt.Errorf("%s.%s: %w", driver, name, err)
+			}
+
+			res[driver][name] = c
+		}
+	}
+
+	*instances = res
+
+	return nil
+}
+
+// UnmarshalJSON decodes instance sections by json tags of the driver configs.
+func (instances *DriverInstances) UnmarshalJSON(data []byte) error {
+	var drivers map[string]map[string]json.RawMessage
+	if err := json.Unmarshal(data, &drivers); err != nil {
+		return err
+	}
+
+	res := make(DriverInstances, len(drivers))
+
+	for driver, sections := range drivers {
+		res[driver] = make(map[string]DriverConfig, len(sections))
+
+		for name, section := range sections {
+			c, err := newDriverConfig(driver)
+			if err != nil {
+				return err
+			}
+
+			if err := json.Unmarshal(section, c); err != nil {
+				return fmt.Errorf("%s.%s: %w", driver, name, err)
+			}
+
+			res[driver][name] = c
+		}
+	}
+
+	*instances = res
+
+	return nil
+}
+
+// newDriverConfig returns new config of the registered driver. Built-in
+// drivers are configured by their own sections.
+func newDriverConfig(name string) (DriverConfig, error) {
+	d, ok := Lookup(name)
+	if !ok || isBuiltin(name) {
+		return nil, fmt.Errorf("%s: %w", name, ErrUnknownDriver)
+	}
+
+	return d.NewConfig(), nil
+}
+
+// section is config of the connection instance.
+type section struct {
+	// id is the driver name for the default instance and the driver name
+	// joined with the instance name by dot for the named ones.
+	id string
+	// name is the instance name, DefaultInstance for the driver section.
+	name string
+	// path is the config path of the section used in validation errors.
+	path   string
+	driver *Driver
+	config DriverConfig
+}
+
+// sections returns configured instances in order of built-in drivers followed
+// by registered drivers sorted by names. Default instance of the driver goes
+// first and named ones follow it sorted by names.
+func (cfg *Config) sections() []section {
+	defaults := []DriverConfig{cfg.Postgres, cfg.Clickhouse, cfg.Elasticsearch, cfg.Redis, cfg.RabbitMQ}
+	named := []interface{}{
+		cfg.Instances.Postgres, cfg.Instances.Clickhouse, cfg.Instances.Elasticsearch,
+		cfg.Instances.Redis, cfg.Instances.RabbitMQ,
+	}
+
+	var sections []section
+
+	for i, d := range builtins {
+		if !reflect.ValueOf(defaults[i]).IsNil() {
+			sections = append(sections, section{
+				id:     d.Name,
+				name:   DefaultInstance,
+				path:   d.Name,
+				driver: d,
+				config: defaults[i],
+			})
+		}
+
+		instances := reflect.ValueOf(named[i])
+		names := make([]string, 0, instances.Len())
+
+		for _, key := range instances.MapKeys() {
+			names = append(names, key.String())
+		}
+
+		sort.Strings(names)
+
+		for _, name := range names {
+			c := instances.MapIndex(reflect.ValueOf(name))
+			if c.IsNil() {
+				continue
+			}
+
+			sections = append(sections, section{
+				id:     d.Name + "." + name,
+				name:   name,
+				path:   "instances." + d.Name + "." + name,
+				driver: d,
+				config: c.Interface().(DriverConfig),
+			})
+		}
+	}
+
+	for _, driver := range cfg.registered() {
+		d, ok := Lookup(driver)
+		if !ok || isBuiltin(driver) {
+			continue
+		}
+
+		if c := cfg.Drivers[driver]; !isNilConfig(c) {
+			sections = append(sections, section{
+				id:     driver,
+				name:   DefaultInstance,
+				path:   "drivers." + driver,
+				driver: d,
+				config: c,
+			})
+		}
+
+		instances := cfg.Instances.Drivers[driver]
+		names := make([]string, 0, len(instances))
+
+		for name := range instances {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+		for _, name := range names {
+			if c := instances[name]; !isNilConfig(c) {
+				sections = append(sections, section{
+					id:     driver + "." + name,
+					name:   name,
+					path:   "instances.drivers." + driver + "." + name,
+					driver: d,
+					config: c,
+				})
+			}
+		}
+	}
+
+	return sections
+}
+
+// registered returns sorted names of the registered drivers configured by
+// drivers and instances.drivers sections.
+func (cfg *Config) registered() []string {
+	set := make(map[string]bool)
+
+	for name := range cfg.Drivers {
+		set[name] = true
+	}
+
+	for name := range cfg.Instances.Drivers {
+		set[name] = true
+	}
+
+	names := make([]string, 0, len(set))
+
+	for name := range set {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// isNilConfig reports whether the section of the registered driver is empty,
+// e.g. null in the config file.
+func isNilConfig(c DriverConfig) bool {
+	return c == nil || reflect.ValueOf(c).IsNil()
+}
+
+// section returns configured instance by id.
+func (cfg *Config) section(id string) (section, bool) {
+	for _, s := range cfg.sections() {
+		if s.id == id {
+			return s, true
+		}
+	}
+
+	return section{}, false
+}
+
+// instanceID returns id of the instance by driver and instance names.
+func instanceID(driver, name string) string {
+	if name == "" || name == DefaultInstance {
+		return driver
+	}
+
+	return driver + "." + name
+}
+
+// ReconnectConfig contains settings of the lost connections handling.
+type ReconnectConfig struct {
+	// Enabled starts background reconnection of the connection which check
+	// has failed. The lost connection is replaced by the new one on success.
+	Enabled bool           `yaml:"enabled" json:"enabled"`
+	Backoff backoff.Config `yaml:"backoff" json:"backoff"`
+	// OutageWindow is the time the connection can stay lost before its
+	// check error is returned by CheckConnections. Zero means the error is
+	// returned immediately.
+	OutageWindow time.Duration `yaml:"outage_window" json:"outage_window"`
+	// CloseDelay is the time connections replaced by reconnection or reload
+	// are kept open, so queries started on them can finish.
+	CloseDelay time.Duration `yaml:"close_delay" json:"close_delay"`
+}
+
+// SetDefaults sets default values of the fields which are not set.
+func (cfg *ReconnectConfig) SetDefaults() {
+	if cfg.CloseDelay == 0 {
+		cfg.CloseDelay = DefaultCloseDelay
+	}
+
+	cfg.Backoff.SetDefaults()
 }
 
 // Validate checks required fields and validates for allowed values.
-func (cfg *Config) Validate() error {
-	if err := cfg.Postgres.Validate(); err != nil {
-		return fmt.Errorf("postgres: %w", err)
+func (cfg *ReconnectConfig) Validate() error {
+	errs := multierror.New()
+
+	if cfg.OutageWindow < 0 {
+		errs.Append(multierror.Field("outage_window", ErrInvalidOutageWindow))
 	}
 
-	if err := cfg.Clickhouse.Validate(); err != nil {
-		return fmt.Errorf("clickhouse: %w", err)
+	if cfg.CloseDelay < 0 {
+		errs.Append(multierror.Field("close_delay", ErrInvalidCloseDelay))
 	}
 
-	if err := cfg.Elasticsearch.Validate(); err != nil {
-		return fmt.Errorf("elasticsearch: %w", err)
+	errs.Append(multierror.Prefix(cfg.Backoff.Validate(), "backoff"))
+
+	if errs.Len() != 0 {
+		return errs
 	}
 
-	if err := cfg.Redis.Validate(); err != nil {
-		return fmt.Errorf("redis: %w", err)
+	return nil
+}
+
+// SetDefaults sets default values of the fields which are not set.
+func (cfg *Config) SetDefaults() {
+	for _, s := range cfg.sections() {
+		s.config.SetDefaults()
 	}
 
-	if err := cfg.RabbitMQ.Validate(); err != nil {
-		return fmt.Errorf("rabbitmq: %w", err)
+	cfg.Reconnect.SetDefaults()
+}
+
+// Validate checks required fields and validates for allowed values of the
+// configured connections.
+func (cfg *Config) Validate() error {
+	errs := multierror.New()
+
+	for _, s := range cfg.sections() {
+		if s.id != s.driver.Name && !isValidInstanceName(s.name) {
+			errs.Append(multierror.Field(s.path, ErrInvalidInstanceName))
+		}
+
+		errs.Append(multierror.Prefix(s.config.Validate(), s.path))
+	}
+
+	for name := range cfg.Drivers {
+		if _, err := newDriverConfig(name); err != nil {
+			errs.Append(multierror.Field("drivers."+name, ErrUnknownDriver))
+		}
+	}
+
+	for name := range cfg.Instances.Drivers {
+		if _, err := newDriverConfig(name); err != nil {
+			errs.Append(multierror.Field("instances.drivers."+name, ErrUnknownDriver))
+		}
+	}
+
+	errs.Append(multierror.Prefix(cfg.Reconnect.Validate(), "reconnect"))
+
+	if errs.Len() != 0 {
+		return errs
 	}
 
 	return nil
 }
+
+// Enabled returns ids of the enabled connections: driver names for
+// the default instances, e.g. postgres, and driver names joined with
+// instance names by dot for the named ones, e.g. postgres.analytics.
+func (cfg *Config) Enabled() []string {
+	var ids []string
+
+	for _, s := range cfg.sections() {
+		if s.config.IsEnabled() {
+			ids = append(ids, s.id)
+		}
+	}
+
+	return ids
+}
+
+func isValidInstanceName(name string) bool {
+	return name != DefaultInstance && instanceName.MatchString(name)
+}
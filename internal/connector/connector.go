@@ -1,142 +1,444 @@
 package connector
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"io"
+	"reflect"
+	"sync"
+	"time"
 
+	"github.com/outdead/goservice/internal/utils/backoff"
 	"github.com/outdead/goservice/internal/utils/driver/clickhouse"
 	"github.com/outdead/goservice/internal/utils/driver/elasticsearch"
 	"github.com/outdead/goservice/internal/utils/driver/postgres"
 	"github.com/outdead/goservice/internal/utils/driver/rabbit"
 	"github.com/outdead/goservice/internal/utils/driver/redis"
+	"github.com/outdead/goservice/internal/utils/logutil"
 	"github.com/outdead/goservice/internal/utils/multierror"
 )
 
-// Connector is the interface for databases accessing.
+// Connector errors.
+var (
+	// ErrNotConfigured is returned by accessors of the connections which
+	// config sections are absent or disabled.
+	ErrNotConfigured = errors.New("connection is not configured")
+
+	// ErrConfigReloaded is returned by reconnection when the config is
+	// reloaded while connecting.
+	ErrConfigReloaded = errors.New("config is reloaded")
+
+	// ErrClosed is returned by reconnection when the connector is closed
+	// while connecting.
+	ErrClosed = errors.New("connector is closed")
+)
+
+// Connector is the interface for databases accessing. Accessors return
+// connections of the named instances configured in instances section, called
+// without name or with DefaultInstance they return the default ones. They
+// return ErrNotConfigured if the connection is not configured. Connections of
+// the registered drivers are returned by Conn.
 type Connector interface {
 	io.Closer
-	CheckConnections() error
+	CheckConnections(timeout time.Duration) (Report, error)
+	CheckConnectionsContext(ctx context.Context) (Report, error)
 	IsErrNotFound(err error) bool
+	Reload(cfg *Config) error
 
-	PG() *postgres.DB
-	CH() *clickhouse.DB
-	ELA() *elasticsearch.Client
-	Redis() *redis.Client
-	RMQ() *rabbit.Client
+	Conn(driver string, name ...string) (interface{}, error)
+
+	PG(name ...string) (postgres.Database, error)
+	CH(name ...string) (clickhouse.Database, error)
+	ELA(name ...string) (elasticsearch.Database, error)
+	Redis(name ...string) (redis.Store, error)
+	RMQ(name ...string) (rabbit.Broker, error)
 }
 
 type connector struct {
-	pg    *postgres.DB
-	ch    *clickhouse.DB
-	ela   *elasticsearch.Client
-	redis *redis.Client
-	rmq   *rabbit.Client
+	mu     sync.RWMutex
+	config *Config
+	logger *logutil.Entry
+
+	// ctx is canceled by Close or with the parent context set by SetContext
+	// to stop startup retries, reconnections and their checks.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// outages contains start times of the failed checks by connection ids.
+	outages map[string]time.Time
+	// reconnecting contains ids of the connections being reconnected.
+	reconnecting map[string]bool
+
+	// instances contains established connections by ids.
+	instances map[string]*instance
+	// retired contains connections replaced by reconnection or reload with
+	// timers of their close.
+	retired map[*instance]*time.Timer
+}
+
+// Option allows to inject options to Connector.
+type Option func(conn *connector)
+
+// SetLogger injects logger of the reconnection progress.
+func SetLogger(log *logutil.Entry) Option {
+	return func(conn *connector) {
+		conn.logger = log
+	}
+}
+
+// SetContext injects parent context of the connector. Startup retries and
+// reconnections are stopped when ctx is done, e.g. on service shutdown.
+func SetContext(ctx context.Context) Option {
+	return func(conn *connector) {
+		conn.ctx = ctx
+	}
 }
 
-// New establishes new connections from configuration parameters.
-func New(cfg *Config) (Connector, error) {
-	conn := connector{}
-	var err error
+// New establishes new connections from configuration parameters. Absent and
+// disabled connections are skipped.
+func New(cfg *Config, options ...Option) (Connector, error) {
+	conn := newConnector(cfg, options...)
+
+	for _, s := range cfg.sections() {
+		if !s.config.IsEnabled() {
+			continue
+		}
+
+		inst, err := conn.open(s)
+		if err != nil {
+			conn.cancel()
 
-	if conn.pg, err = postgres.NewDB(&cfg.Postgres); err != nil {
-		return nil, conn.close(err)
+			return nil, conn.close(err)
+		}
+
+		conn.instances[s.id] = inst
 	}
 
-	if conn.ch, err = clickhouse.NewDB(&cfg.Clickhouse); err != nil {
-		return nil, conn.close(err)
+	return conn, nil
+}
+
+func newConnector(cfg *Config, options ...Option) *connector {
+	conn := connector{
+		config:       cfg,
+		ctx:          context.Background(),
+		outages:      make(map[string]time.Time),
+		reconnecting: make(map[string]bool),
+		instances:    make(map[string]*instance),
+		retired:      make(map[*instance]*time.Timer),
 	}
 
-	if conn.ela, err = elasticsearch.NewClient(&cfg.Elasticsearch); err != nil {
-		return nil, conn.close(err)
+	for _, option := range options {
+		option(&conn)
 	}
 
-	if conn.redis, err = redis.NewClient(&cfg.Redis); err != nil {
-		return nil, conn.close(err)
+	if conn.logger == nil {
+		conn.logger = logutil.New().NewEntry()
 	}
 
-	if conn.rmq, err = rabbit.NewClient(&cfg.RabbitMQ); err != nil {
-		return nil, conn.close(err)
+	conn.ctx, conn.cancel = context.WithCancel(conn.ctx)
+
+	return &conn
+}
+
+// open establishes connection of the section applying startup retries.
+func (conn *connector) open(s section) (*instance, error) {
+	startup := new(backoff.RetryConfig)
+	if s.driver.Startup != nil {
+		startup = s.driver.Startup(s.config)
 	}
 
-	return &conn, nil
+	return conn.dial(s, startup)
 }
 
-// CheckConnections checks database connections.
-// TODO: Add multierror.
-func (conn *connector) CheckConnections() error {
-	if ok := conn.PG().IsConnected(); !ok {
-		return postgres.ErrLostConnection
+// dial establishes connection of the section with retries. Zero startup
+// config makes the single attempt.
+func (conn *connector) dial(s section, startup *backoff.RetryConfig) (*instance, error) {
+	var c interface{}
+
+	err := conn.connect(s.id, startup, func() (err error) {
+		c, err = s.driver.Connect(s.config)
+
+		return instanceError(s.name, err)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	if ok := conn.CH().IsConnected(); !ok {
-		return clickhouse.ErrLostConnection
+	return &instance{driver: s.driver, conn: c}, nil
+}
+
+// connect calls fn until it succeeds, startup.max_wait of the connection
+// elapses or the connector is closed. Progress is logged.
+func (conn *connector) connect(name string, startup *backoff.RetryConfig, fn func() error) error {
+	attempts := 0
+
+	err := backoff.Retry(conn.ctx, startup, fn, func(err error, attempt int, delay time.Duration) {
+		attempts = attempt
+		conn.logger.WithError(err).Warnf("%s is not available (attempt %d), retry in %s", name, attempt, delay.Round(time.Millisecond))
+	})
+	if err != nil {
+		return err
 	}
 
-	if ok := conn.Redis().IsConnected(); !ok {
-		return redis.ErrLostConnection
+	if attempts != 0 {
+		conn.logger.Infof("connect %s success after %d attempts", name, attempts+1)
 	}
 
 	return nil
 }
 
+// instanceError adds the instance name to errors of the named instances.
+// Errors of the default instances are returned as is.
+func instanceError(name string, err error) error {
+	if err == nil || name == DefaultInstance {
+		return err
+	}
+
+	return fmt.Errorf("%s instance: %w", name, err)
+}
+
 // IsErrNotFound returns true if the passed error indicates that there is
 // no data in the database.
 func (conn *connector) IsErrNotFound(err error) bool {
-	return conn.ELA().IsErrNotFound(err) ||
-		conn.PG().IsErrNoRows(err) ||
-		conn.Redis().IsErrNoRows(err)
+	conn.mu.RLock()
+	defer conn.mu.RUnlock()
+
+	for _, inst := range conn.instances {
+		if inst.driver.IsErrNotFound != nil && inst.driver.IsErrNotFound(inst.conn, err) {
+			return true
+		}
+	}
+
+	return false
 }
 
-// CH returns pointer to clickhouse.DB.
-func (conn *connector) CH() *clickhouse.DB {
-	return conn.ch
+// Reload reconnects to databases which config sections differ from the
+// current config, connects newly configured ones and closes removed and
+// disabled ones after reconnect.close_delay. New connections are established
+// before the old ones are replaced, if any of them fails nothing is changed.
+// Change of RabbitMQ qos is applied without reconnection to the consumers
+// created after the reload.
+func (conn *connector) Reload(cfg *Config) error {
+	conn.mu.RLock()
+	cur := conn.config
+	conn.mu.RUnlock()
+
+	// fresh contains new connections to close them if the reload fails.
+	fresh := make(map[string]*instance)
+	// updated contains ids of the connections which config change is applied
+	// without reconnection.
+	updated := make(map[string]bool)
+
+	for _, s := range cfg.sections() {
+		prev, ok := cur.section(s.id)
+
+		switch {
+		case ok && reflect.DeepEqual(prev.config, s.config):
+			continue
+		case ok && s.driver.CanUpdate != nil && s.driver.CanUpdate(prev.config, s.config):
+			updated[s.id] = true
+
+			continue
+		case !s.config.IsEnabled():
+			continue
+		}
+
+		c, err := s.driver.Connect(s.config)
+		if err != nil {
+			return closeInstances(fresh, instanceError(s.name, err))
+		}
+
+		fresh[s.id] = &instance{driver: s.driver, conn: c}
+	}
+
+	conn.retire(conn.swap(cfg, fresh, updated))
+
+	return nil
 }
 
-// PG returns pointer to postgres.DB.
-func (conn *connector) PG() *postgres.DB {
-	return conn.pg
+// swap replaces connections by fresh ones, applies config updates, drops
+// removed and disabled connections and returns replaced and dropped ones.
+// Kept connections are taken under the lock, so connections replaced by
+// reconnection are not lost.
+func (conn *connector) swap(cfg *Config, fresh map[string]*instance, updated map[string]bool) map[string]*instance {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	next := make(map[string]*instance)
+
+	for _, s := range cfg.sections() {
+		if !s.config.IsEnabled() {
+			continue
+		}
+
+		if inst, ok := fresh[s.id]; ok {
+			next[s.id] = inst
+
+			continue
+		}
+
+		if inst, ok := conn.instances[s.id]; ok {
+			next[s.id] = inst
+
+			if updated[s.id] {
+				s.driver.Update(inst.conn, s.config)
+			}
+		}
+	}
+
+	old := make(map[string]*instance)
+
+	for id, inst := range conn.instances {
+		if next[id] != inst {
+			old[id] = inst
+		}
+	}
+
+	conn.instances = next
+	conn.config = cfg
+
+	return old
 }
 
-// ELA returns pointer to elasticsearch.Client.
-func (conn *connector) ELA() *elasticsearch.Client {
-	return conn.ela
+// Conn returns connection of the driver instance by name. Type of the
+// connection is defined by the driver.
+func (conn *connector) Conn(driver string, name ...string) (interface{}, error) {
+	id := driver
+	if len(name) != 0 {
+		id = instanceID(driver, name[0])
+	}
+
+	conn.mu.RLock()
+	defer conn.mu.RUnlock()
+
+	inst, ok := conn.instances[id]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", id, ErrNotConfigured)
+	}
+
+	return inst.conn, nil
 }
 
-// Redis returns pointer to redis.Client.
-func (conn *connector) Redis() *redis.Client {
-	return conn.redis
+// CH returns ClickHouse database.
+func (conn *connector) CH(name ...string) (clickhouse.Database, error) {
+	c, err := conn.Conn(Clickhouse, name...)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.(clickhouse.Database), nil
 }
 
-// RMQ returns pointer to rabbit.Client.
-func (conn *connector) RMQ() *rabbit.Client {
-	return conn.rmq
+// PG returns PostgreSQL database.
+func (conn *connector) PG(name ...string) (postgres.Database, error) {
+	c, err := conn.Conn(Postgres, name...)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.(postgres.Database), nil
 }
 
-// Close closes all databases connections.
-func (conn *connector) Close() error {
-	return conn.close()
+// ELA returns Elasticsearch database.
+func (conn *connector) ELA(name ...string) (elasticsearch.Database, error) {
+	c, err := conn.Conn(Elasticsearch, name...)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.(elasticsearch.Database), nil
 }
 
-func (conn *connector) close(prevErrs ...error) error {
-	errs := multierror.New(prevErrs...)
+// Redis returns Redis store.
+func (conn *connector) Redis(name ...string) (redis.Store, error) {
+	c, err := conn.Conn(Redis, name...)
+	if err != nil {
+		return nil, err
+	}
 
-	if conn.pg != nil {
-		if err := conn.pg.Close(); err != nil {
-			errs.Append(err)
+	return c.(redis.Store), nil
+}
+
+// RMQ returns RabbitMQ broker.
+func (conn *connector) RMQ(name ...string) (rabbit.Broker, error) {
+	c, err := conn.Conn(RabbitMQ, name...)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.(rabbit.Broker), nil
+}
+
+// Close stops reconnections and closes all databases connections including
+// retired ones.
+func (conn *connector) Close() error {
+	conn.mu.Lock()
+	conn.cancel()
+
+	retired := conn.retired
+	conn.retired = make(map[*instance]*time.Timer)
+
+	conn.mu.Unlock()
+
+	for inst, timer := range retired {
+		// Retired connections are not used anymore and their close errors
+		// do not matter.
+		if timer.Stop() {
+			_ = inst.Close()
 		}
 	}
 
-	if conn.ch != nil {
-		if err := conn.ch.Close(); err != nil {
-			errs.Append(err)
+	return conn.close()
+}
+
+// retire closes replaced connections after reconnect.close_delay, so queries
+// started on them before the replacement can finish. Connections retired
+// after Close are closed immediately. Close errors do not matter because the
+// connections are not used anymore.
+func (conn *connector) retire(instances map[string]*instance) {
+	var closing []*instance
+
+	conn.mu.Lock()
+
+	delay := conn.config.Reconnect.CloseDelay
+
+	for _, inst := range instances {
+		if conn.isClosed() || delay <= 0 {
+			closing = append(closing, inst)
+
+			continue
 		}
+
+		inst := inst
+
+		conn.retired[inst] = time.AfterFunc(delay, func() {
+			conn.mu.Lock()
+			delete(conn.retired, inst)
+			conn.mu.Unlock()
+
+			_ = inst.Close()
+		})
 	}
 
-	if conn.ela != nil {
-		conn.ela.Close()
+	conn.mu.Unlock()
+
+	for _, inst := range closing {
+		_ = inst.Close()
 	}
+}
+
+func (conn *connector) close(prevErrs ...error) error {
+	return closeInstances(conn.instances, prevErrs...)
+}
+
+// closeInstances closes connections and returns multierror with previous
+// errors and close errors or nil.
+func closeInstances(instances map[string]*instance, prevErrs ...error) error {
+	errs := multierror.New(prevErrs...)
 
-	if conn.redis != nil {
-		if err := conn.redis.Close(); err != nil {
+	for _, inst := range instances {
+		if err := inst.Close(); err != nil {
 			errs.Append(err)
 		}
 	}
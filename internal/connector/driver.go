@@ -0,0 +1,299 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/outdead/goservice/internal/utils/backoff"
+	"github.com/outdead/goservice/internal/utils/driver/clickhouse"
+	"github.com/outdead/goservice/internal/utils/driver/elasticsearch"
+	"github.com/outdead/goservice/internal/utils/driver/postgres"
+	"github.com/outdead/goservice/internal/utils/driver/rabbit"
+	"github.com/outdead/goservice/internal/utils/driver/redis"
+)
+
+// Names of the built-in drivers. They are keys of the connections config
+// and names of the default instances in the checks report.
+const (
+	Postgres      = "postgres"
+	Clickhouse    = "clickhouse"
+	Elasticsearch = "elasticsearch"
+	Redis         = "redis"
+	RabbitMQ      = "rabbitmq"
+)
+
+// Registry errors.
+var (
+	// ErrUnknownDriver is returned when connections.drivers contains section
+	// of the driver which is not registered.
+	ErrUnknownDriver = errors.New("driver is not registered")
+
+	// ErrLostConnection is the default errLost of the registered drivers.
+	ErrLostConnection = errors.New("connection is lost")
+)
+
+// DriverConfig is implemented by pointers to the driver configs.
+type DriverConfig interface {
+	// IsEnabled reports whether the connection must be established. It is
+	// called on nil pointers of the absent sections.
+	IsEnabled() bool
+	SetDefaults()
+	Validate() error
+}
+
+// Driver describes how connections of the database are established, checked
+// and closed. Connections are returned by Conn accessor of the Connector as
+// is, driver packages usually wrap it to return the concrete type.
+type Driver struct {
+	// Name is the key of the driver section in connections.drivers config
+	// and the connection name in the checks report.
+	Name string
+
+	// NewConfig returns pointer to new empty config of the driver. The driver
+	// config section is decoded to it.
+	NewConfig func() DriverConfig
+
+	// Connect establishes new connection.
+	Connect func(cfg DriverConfig) (interface{}, error)
+
+	// IsConnected checks the connection. The check must be aborted when ctx
+	// is done.
+	IsConnected func(ctx context.Context, conn interface{}) bool
+
+	// Close closes the connection.
+	Close func(conn interface{}) error
+
+	// ErrLost is reported for the connection which check failed. It is
+	// ErrLostConnection prefixed by the driver name if not set.
+	ErrLost error
+
+	// Startup returns startup retries config of the connection. Connection
+	// is established without retries if it is nil.
+	Startup func(cfg DriverConfig) *backoff.RetryConfig
+
+	// IsErrNotFound is nil if the database has no "not found" error.
+	IsErrNotFound func(conn interface{}, err error) bool
+
+	// CanUpdate reports whether config change can be applied to the
+	// established connection by Update without reconnection. Both are nil
+	// if any change requires reconnection.
+	CanUpdate func(cur, next DriverConfig) bool
+	Update    func(conn interface{}, cfg DriverConfig)
+
+	// Reconnectable is false for drivers which clients restore connections
+	// by themselves.
+	Reconnectable bool
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]*Driver)
+)
+
+// Register makes the driver available for connections.drivers config by the
+// name. It is intended to be called from the init function of the driver
+// package. The connector is internal, so driver packages must be placed in
+// this module. Register panics if the driver is registered twice or its
+// required functions are nil.
+func Register(d *Driver) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if d.Name == "" || d.NewConfig == nil || d.Connect == nil || d.IsConnected == nil || d.Close == nil {
+		panic("connector: Register driver " + d.Name + " is incomplete")
+	}
+
+	if _, ok := registry[d.Name]; ok {
+		panic("connector: Register called twice for driver " + d.Name)
+	}
+
+	if d.ErrLost == nil {
+		d.ErrLost = fmt.Errorf("%s: %w", d.Name, ErrLostConnection)
+	}
+
+	registry[d.Name] = d
+}
+
+// Lookup returns registered driver by name.
+func Lookup(name string) (*Driver, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	d, ok := registry[name]
+
+	return d, ok
+}
+
+// Drivers returns sorted names of the registered drivers including built-in
+// ones.
+func Drivers() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func init() {
+	for _, d := range builtins {
+		Register(d)
+	}
+}
+
+// isBuiltin reports whether the driver is configured by its own section of
+// the connections config.
+func isBuiltin(name string) bool {
+	for _, d := range builtins {
+		if d.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// builtins contains built-in drivers in order of connection.
+var builtins = []*Driver{
+	{
+		Name:          Postgres,
+		ErrLost:       postgres.ErrLostConnection,
+		Reconnectable: true,
+		NewConfig: func() DriverConfig {
+			return new(postgres.Config)
+		},
+		Startup: func(cfg DriverConfig) *backoff.RetryConfig {
+			return &cfg.(*postgres.Config).Startup
+		},
+		Connect: func(cfg DriverConfig) (interface{}, error) {
+			return postgres.NewDB(cfg.(*postgres.Config))
+		},
+		IsConnected: func(ctx context.Context, conn interface{}) bool {
+			return conn.(*postgres.DB).IsConnectedContext(ctx)
+		},
+		Close: func(conn interface{}) error {
+			return conn.(*postgres.DB).Close()
+		},
+		IsErrNotFound: func(conn interface{}, err error) bool {
+			return conn.(*postgres.DB).IsErrNoRows(err)
+		},
+	},
+	{
+		Name:          Clickhouse,
+		ErrLost:       clickhouse.ErrLostConnection,
+		Reconnectable: true,
+		NewConfig: func() DriverConfig {
+			return new(clickhouse.Config)
+		},
+		Startup: func(cfg DriverConfig) *backoff.RetryConfig {
+			return &cfg.(*clickhouse.Config).Startup
+		},
+		Connect: func(cfg DriverConfig) (interface{}, error) {
+			return clickhouse.NewDB(cfg.(*clickhouse.Config))
+		},
+		IsConnected: func(ctx context.Context, conn interface{}) bool {
+			return conn.(*clickhouse.DB).IsConnectedContext(ctx)
+		},
+		Close: func(conn interface{}) error {
+			return conn.(*clickhouse.DB).Close()
+		},
+	},
+	{
+		Name:    Elasticsearch,
+		ErrLost: elasticsearch.ErrLostConnection,
+		NewConfig: func() DriverConfig {
+			return new(elasticsearch.Config)
+		},
+		Startup: func(cfg DriverConfig) *backoff.RetryConfig {
+			return &cfg.(*elasticsearch.Config).Startup
+		},
+		Connect: func(cfg DriverConfig) (interface{}, error) {
+			return elasticsearch.NewClient(cfg.(*elasticsearch.Config))
+		},
+		IsConnected: func(ctx context.Context, conn interface{}) bool {
+			return conn.(*elasticsearch.Client).IsConnectedContext(ctx)
+		},
+		Close: func(conn interface{}) error {
+			conn.(*elasticsearch.Client).Close()
+
+			return nil
+		},
+		IsErrNotFound: func(conn interface{}, err error) bool {
+			return conn.(*elasticsearch.Client).IsErrNotFound(err)
+		},
+	},
+	{
+		Name:          Redis,
+		ErrLost:       redis.ErrLostConnection,
+		Reconnectable: true,
+		NewConfig: func() DriverConfig {
+			return new(redis.Config)
+		},
+		Startup: func(cfg DriverConfig) *backoff.RetryConfig {
+			return &cfg.(*redis.Config).Startup
+		},
+		Connect: func(cfg DriverConfig) (interface{}, error) {
+			return redis.NewClient(cfg.(*redis.Config))
+		},
+		IsConnected: func(ctx context.Context, conn interface{}) bool {
+			return conn.(*redis.Client).IsConnectedContext(ctx)
+		},
+		Close: func(conn interface{}) error {
+			return conn.(*redis.Client).Close()
+		},
+		IsErrNotFound: func(conn interface{}, err error) bool {
+			return conn.(*redis.Client).IsErrNoRows(err)
+		},
+	},
+	{
+		Name:    RabbitMQ,
+		ErrLost: rabbit.ErrLostConnection,
+		NewConfig: func() DriverConfig {
+			return new(rabbit.Config)
+		},
+		Startup: func(cfg DriverConfig) *backoff.RetryConfig {
+			return &cfg.(*rabbit.Config).Startup
+		},
+		Connect: func(cfg DriverConfig) (interface{}, error) {
+			return rabbit.NewClient(cfg.(*rabbit.Config))
+		},
+		IsConnected: func(ctx context.Context, conn interface{}) bool {
+			return conn.(*rabbit.Client).IsConnectedContext(ctx)
+		},
+		Close: func(conn interface{}) error {
+			conn.(*rabbit.Client).Close()
+
+			return nil
+		},
+		// Qos is used on consumers creation only and can be changed live.
+		CanUpdate: func(cur, next DriverConfig) bool {
+			rmq := *cur.(*rabbit.Config)
+			rmq.Server.Qos = next.(*rabbit.Config).Server.Qos
+
+			return reflect.DeepEqual(&rmq, next)
+		},
+		Update: func(conn interface{}, cfg DriverConfig) {
+			conn.(*rabbit.Client).SetQos(cfg.(*rabbit.Config).Server.Qos)
+		},
+	},
+}
+
+// instance is established connection.
+type instance struct {
+	driver *Driver
+	conn   interface{}
+}
+
+// Close closes the connection.
+func (inst *instance) Close() error {
+	return inst.driver.Close(inst.conn)
+}
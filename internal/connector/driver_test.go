@@ -0,0 +1,321 @@
+package connector_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/outdead/goservice/internal/connector"
+	"github.com/outdead/goservice/internal/utils/backoff"
+	"github.com/outdead/goservice/internal/utils/configutil"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+// Names of the test drivers registered in init.
+const (
+	alpha = "alpha"
+	beta  = "beta"
+)
+
+var (
+	errEmptyAddr = errors.New("addr is empty")
+	errRefused   = errors.New("connection refused")
+)
+
+func init() {
+	connector.Register(testDriver(alpha))
+	connector.Register(testDriver(beta))
+}
+
+// TestConfig is the config of the test drivers.
+type TestConfig struct {
+	Disabled bool   `yaml:"disabled" json:"disabled"`
+	Addr     string `yaml:"addr" json:"addr"`
+
+	Startup backoff.RetryConfig `yaml:"startup" json:"startup"`
+}
+
+func (cfg *TestConfig) IsEnabled() bool { return cfg != nil && !cfg.Disabled }
+
+func (cfg *TestConfig) SetDefaults() {}
+
+func (cfg *TestConfig) Validate() error {
+	if cfg.Addr == "" {
+		return errEmptyAddr
+	}
+
+	return nil
+}
+
+// Server is the fake database the test drivers connect to by address.
+type Server struct {
+	mu      sync.Mutex
+	down    bool
+	refuse  bool
+	latency time.Duration
+	// conns counts established connections.
+	conns int
+}
+
+// SetDown makes checks of the established connections fail.
+func (s *Server) SetDown(down bool) {
+	s.mu.Lock()
+	s.down = down
+	s.mu.Unlock()
+}
+
+// SetRefuse makes new connections fail.
+func (s *Server) SetRefuse(refuse bool) {
+	s.mu.Lock()
+	s.refuse = refuse
+	s.mu.Unlock()
+}
+
+// SetLatency delays checks of the established connections.
+func (s *Server) SetLatency(latency time.Duration) {
+	s.mu.Lock()
+	s.latency = latency
+	s.mu.Unlock()
+}
+
+// Conns returns number of the established connections.
+func (s *Server) Conns() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.conns
+}
+
+var (
+	serversMu sync.Mutex
+	servers   = make(map[string]*Server)
+)
+
+// NewServer starts the fake database and returns its address.
+func NewServer() (string, *Server) {
+	serversMu.Lock()
+	defer serversMu.Unlock()
+
+	addr := fmt.Sprintf("server-%d", len(servers))
+	servers[addr] = &Server{}
+
+	return addr, servers[addr]
+}
+
+// Conn is the connection of the test drivers.
+type Conn struct {
+	server *Server
+	closed int32
+	broken int32
+}
+
+// Break makes checks of the connection fail while the server is up.
+func (c *Conn) Break() {
+	atomic.StoreInt32(&c.broken, 1)
+}
+
+// Closed reports whether the connection is closed by the connector.
+func (c *Conn) Closed() bool {
+	return atomic.LoadInt32(&c.closed) == 1
+}
+
+func testDriver(name string) *connector.Driver {
+	return &connector.Driver{
+		Name:          name,
+		Reconnectable: true,
+		NewConfig: func() connector.DriverConfig {
+			return new(TestConfig)
+		},
+		Startup: func(cfg connector.DriverConfig) *backoff.RetryConfig {
+			return &cfg.(*TestConfig).Startup
+		},
+		Connect: func(cfg connector.DriverConfig) (interface{}, error) {
+			serversMu.Lock()
+			s, ok := servers[cfg.(*TestConfig).Addr]
+			serversMu.Unlock()
+
+			if !ok {
+				return nil, errRefused
+			}
+
+			s.mu.Lock()
+			defer s.mu.Unlock()
+
+			if s.refuse {
+				return nil, errRefused
+			}
+
+			s.conns++
+
+			return &Conn{server: s}, nil
+		},
+		IsConnected: func(ctx context.Context, conn interface{}) bool {
+			c := conn.(*Conn)
+
+			c.server.mu.Lock()
+			down, latency := c.server.down, c.server.latency
+			c.server.mu.Unlock()
+
+			select {
+			case <-ctx.Done():
+				return false
+			case <-time.After(latency):
+			}
+
+			return !down && !c.Closed() && atomic.LoadInt32(&c.broken) == 0
+		},
+		Close: func(conn interface{}) error {
+			atomic.StoreInt32(&conn.(*Conn).closed, 1)
+
+			return nil
+		},
+	}
+}
+
+func TestRegister(t *testing.T) {
+	tests := []struct {
+		name   string
+		driver *connector.Driver
+	}{
+		{"registered twice", testDriver(alpha)},
+		{"built-in name", testDriver(connector.Postgres)},
+		{"incomplete", &connector.Driver{Name: "gamma"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Panics(t, func() { connector.Register(tt.driver) })
+		})
+	}
+
+	_, ok := connector.Lookup("gamma")
+	assert.False(t, ok, "incomplete driver is registered")
+}
+
+func TestLookup(t *testing.T) {
+	d, ok := connector.Lookup(alpha)
+	if assert.True(t, ok) {
+		assert.Equal(t, alpha, d.Name)
+		assert.True(t, errors.Is(d.ErrLost, connector.ErrLostConnection), "default ErrLost is not set")
+	}
+
+	_, ok = connector.Lookup("mongodb")
+	assert.False(t, ok)
+
+	assert.Subset(t, connector.Drivers(), []string{alpha, beta, connector.Postgres, connector.RabbitMQ})
+}
+
+func TestNew_RegisteredDriver(t *testing.T) {
+	addr, s := NewServer()
+
+	data := `
+drivers:
+  alpha:
+    addr: ` + addr + `
+instances:
+  drivers:
+    alpha:
+      analytics:
+        addr: ` + addr + `
+      reports:
+        disabled: true
+        addr: ` + addr + `
+`
+
+	var cfg connector.Config
+	if err := yaml.Unmarshal([]byte(data), &cfg); !assert.NoError(t, err) {
+		return
+	}
+
+	cfg.SetDefaults()
+
+	if err := cfg.Validate(); !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, []string{"alpha", "alpha.analytics"}, cfg.Enabled())
+
+	conn, err := connector.New(&cfg)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, 2, s.Conns())
+
+	def, err := conn.Conn(alpha)
+	assert.NoError(t, err)
+
+	named, err := conn.Conn(alpha, "analytics")
+	assert.NoError(t, err)
+	assert.NotSame(t, def, named)
+
+	_, err = conn.Conn(alpha, "reports")
+	assert.True(t, errors.Is(err, connector.ErrNotConfigured), "disabled instance is connected: %v", err)
+
+	report, err := conn.CheckConnections(time.Second)
+	assert.NoError(t, err)
+
+	if assert.Len(t, report, 2) {
+		assert.Equal(t, "alpha", report[0].Name)
+		assert.Equal(t, "alpha.analytics", report[1].Name)
+	}
+
+	assert.NoError(t, conn.Close())
+	assert.True(t, def.(*Conn).Closed())
+	assert.True(t, named.(*Conn).Closed())
+}
+
+func TestConfig_RegisteredDriver_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		err  error
+	}{
+		{"unknown driver", "drivers:\n  mongodb:\n    uri: mongodb://mongo\n", connector.ErrUnknownDriver},
+		{"unknown instance driver", "instances:\n  drivers:\n    mongodb:\n      analytics: {}\n", connector.ErrUnknownDriver},
+		{"built-in driver", "drivers:\n  postgres:\n    addr: db:5432\n", connector.ErrUnknownDriver},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg connector.Config
+
+			err := yaml.Unmarshal([]byte(tt.data), &cfg)
+			assert.True(t, errors.Is(err, tt.err), "expected %v, got %v", tt.err, err)
+		})
+	}
+}
+
+func TestConfig_RegisteredDriver_UnknownKeys(t *testing.T) {
+	data := `
+drivers:
+  alpha:
+    adr: server
+instances:
+  drivers:
+    alpha:
+      analytics:
+        addr: server
+        disable: true
+`
+
+	var cfg connector.Config
+	if err := yaml.Unmarshal([]byte(data), &cfg); !assert.NoError(t, err) {
+		return
+	}
+
+	var tree interface{}
+	if err := yaml.Unmarshal([]byte(data), &tree); !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, []string{
+		"drivers.alpha.adr",
+		"instances.drivers.alpha.analytics.disable",
+	}, configutil.UnknownKeys(&cfg, tree, "yaml"))
+}
@@ -0,0 +1,66 @@
+package fake
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Insert contains query and rows passed to MultiInsert.
+type Insert struct {
+	Query string
+	Rows  [][]interface{}
+}
+
+// Clickhouse is in-memory clickhouse.Database which records inserts.
+type Clickhouse struct {
+	failer
+
+	mu      sync.Mutex
+	inserts []Insert
+}
+
+// NewClickhouse creates new Clickhouse.
+func NewClickhouse() *Clickhouse {
+	return new(Clickhouse)
+}
+
+// GetServerTime returns local time.
+func (db *Clickhouse) GetServerTime() (time.Time, error) {
+	return db.GetServerTimeContext(context.Background())
+}
+
+// GetServerTimeContext returns local time or ctx error if ctx is done.
+func (db *Clickhouse) GetServerTimeContext(ctx context.Context) (time.Time, error) {
+	if err := db.failureContext(ctx); err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Now(), nil
+}
+
+// MultiInsert records the insert.
+func (db *Clickhouse) MultiInsert(query string, rows [][]interface{}) error {
+	return db.MultiInsertContext(context.Background(), query, rows)
+}
+
+// MultiInsertContext records the insert or returns ctx error if ctx is done.
+func (db *Clickhouse) MultiInsertContext(ctx context.Context, query string, rows [][]interface{}) error {
+	if err := db.failureContext(ctx); err != nil {
+		return err
+	}
+
+	db.mu.Lock()
+	db.inserts = append(db.inserts, Insert{Query: query, Rows: append([][]interface{}(nil), rows...)})
+	db.mu.Unlock()
+
+	return nil
+}
+
+// Inserts returns recorded inserts in order of calls.
+func (db *Clickhouse) Inserts() []Insert {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return append([]Insert(nil), db.inserts...)
+}
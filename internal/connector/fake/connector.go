@@ -0,0 +1,342 @@
+// Package fake contains in-memory implementation of connector.Connector for
+// unit tests. Fakes record written data to assert against and can be made to
+// fail, e.g.
+//
+//	conn := fake.New()
+//	ch := conn.AddClickhouse()
+//	// ... run the code under test with conn ...
+//	assert.Len(t, ch.Inserts(), 1)
+package fake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/outdead/goservice/internal/connector"
+	"github.com/outdead/goservice/internal/utils/driver/clickhouse"
+	"github.com/outdead/goservice/internal/utils/driver/elasticsearch"
+	"github.com/outdead/goservice/internal/utils/driver/postgres"
+	"github.com/outdead/goservice/internal/utils/driver/rabbit"
+	"github.com/outdead/goservice/internal/utils/driver/redis"
+)
+
+// ErrUnexpectedType is returned by accessors of the built-in drivers when the
+// connection added by Add does not implement the driver interface.
+var ErrUnexpectedType = errors.New("connection does not implement the driver interface")
+
+// Connector is in-memory connector.Connector. Connections are added by Add
+// methods. Accessors of the connections which are not added return
+// connector.ErrNotConfigured.
+type Connector struct {
+	mu     sync.RWMutex
+	config *connector.Config
+	closed bool
+
+	// ids contains ids of the added connections in order of adding.
+	ids   []string
+	conns map[string]interface{}
+	lost  map[string]bool
+}
+
+// New creates new Connector without connections.
+func New() *Connector {
+	return &Connector{
+		config: new(connector.Config),
+		conns:  make(map[string]interface{}),
+		lost:   make(map[string]bool),
+	}
+}
+
+// Add adds connection of the driver instance. The connection is returned by
+// Conn as is and by accessors of the built-in drivers as their interfaces,
+// accessors return ErrUnexpectedType if it does not implement them. Called
+// without name it adds the default instance.
+func (c *Connector) Add(driver string, conn interface{}, name ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id := instanceID(driver, name)
+	if _, ok := c.conns[id]; !ok {
+		c.ids = append(c.ids, id)
+	}
+
+	c.conns[id] = conn
+}
+
+// AddPostgres adds new Postgres and returns it.
+func (c *Connector) AddPostgres(name ...string) *Postgres {
+	db := NewPostgres()
+	c.Add(connector.Postgres, db, name...)
+
+	return db
+}
+
+// AddClickhouse adds new Clickhouse and returns it.
+func (c *Connector) AddClickhouse(name ...string) *Clickhouse {
+	db := NewClickhouse()
+	c.Add(connector.Clickhouse, db, name...)
+
+	return db
+}
+
+// AddElasticsearch adds new Elasticsearch and returns it.
+func (c *Connector) AddElasticsearch(name ...string) *Elasticsearch {
+	client := NewElasticsearch()
+	c.Add(connector.Elasticsearch, client, name...)
+
+	return client
+}
+
+// AddRedis adds new Redis and returns it.
+func (c *Connector) AddRedis(name ...string) *Redis {
+	client := NewRedis()
+	c.Add(connector.Redis, client, name...)
+
+	return client
+}
+
+// AddRabbitMQ adds new RabbitMQ and returns it.
+func (c *Connector) AddRabbitMQ(name ...string) *RabbitMQ {
+	client := NewRabbitMQ()
+	c.Add(connector.RabbitMQ, client, name...)
+
+	return client
+}
+
+// Lose makes check of the connection fail until Restore is called.
+func (c *Connector) Lose(driver string, name ...string) {
+	c.mu.Lock()
+	c.lost[instanceID(driver, name)] = true
+	c.mu.Unlock()
+}
+
+// Restore makes check of the lost connection pass.
+func (c *Connector) Restore(driver string, name ...string) {
+	c.mu.Lock()
+	delete(c.lost, instanceID(driver, name))
+	c.mu.Unlock()
+}
+
+// CheckConnections returns report with status of each added connection in
+// order of adding. Connections lost by Lose are reported as failed and
+// returned in recoverable error.
+func (c *Connector) CheckConnections(_ time.Duration) (connector.Report, error) {
+	return c.CheckConnectionsContext(context.Background())
+}
+
+// CheckConnectionsContext returns report as CheckConnections does or ctx
+// error if ctx is done.
+func (c *Connector) CheckConnectionsContext(ctx context.Context) (connector.Report, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	report := make(connector.Report, 0, len(c.ids))
+
+	for _, id := range c.ids {
+		status := connector.Status{Name: id, OK: !c.lost[id]}
+		if !status.OK {
+			status.Err = fmt.Errorf("%s: %w", id, connector.ErrLostConnection)
+		}
+
+		report = append(report, status)
+	}
+
+	return report, report.Err()
+}
+
+// IsErrNotFound returns true if any of the added connections recognizes the
+// error as "not found" one.
+func (c *Connector) IsErrNotFound(err error) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, conn := range c.conns {
+		switch conn := conn.(type) {
+		case interface{ IsErrNoRows(err error) bool }:
+			if conn.IsErrNoRows(err) {
+				return true
+			}
+		case interface{ IsErrNotFound(err error) bool }:
+			if conn.IsErrNotFound(err) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Reload stores the config. Connections are not changed.
+func (c *Connector) Reload(cfg *connector.Config) error {
+	c.mu.Lock()
+	c.config = cfg
+	c.mu.Unlock()
+
+	return nil
+}
+
+// Config returns the config passed to the last Reload.
+func (c *Connector) Config() *connector.Config {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.config
+}
+
+// Conn returns connection of the driver instance by name.
+func (c *Connector) Conn(driver string, name ...string) (interface{}, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	id := instanceID(driver, name)
+
+	conn, ok := c.conns[id]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", id, connector.ErrNotConfigured)
+	}
+
+	return conn, nil
+}
+
+// PG returns postgres.Database added by Add or AddPostgres.
+func (c *Connector) PG(name ...string) (postgres.Database, error) {
+	conn, err := c.Conn(connector.Postgres, name...)
+	if err != nil {
+		return nil, err
+	}
+
+	res, ok := conn.(postgres.Database)
+	if !ok {
+		return nil, unexpectedType(connector.Postgres, name, conn)
+	}
+
+	return res, nil
+}
+
+// CH returns clickhouse.Database added by Add or AddClickhouse.
+func (c *Connector) CH(name ...string) (clickhouse.Database, error) {
+	conn, err := c.Conn(connector.Clickhouse, name...)
+	if err != nil {
+		return nil, err
+	}
+
+	res, ok := conn.(clickhouse.Database)
+	if !ok {
+		return nil, unexpectedType(connector.Clickhouse, name, conn)
+	}
+
+	return res, nil
+}
+
+// ELA returns elasticsearch.Database added by Add or AddElasticsearch.
+func (c *Connector) ELA(name ...string) (elasticsearch.Database, error) {
+	conn, err := c.Conn(connector.Elasticsearch, name...)
+	if err != nil {
+		return nil, err
+	}
+
+	res, ok := conn.(elasticsearch.Database)
+	if !ok {
+		return nil, unexpectedType(connector.Elasticsearch, name, conn)
+	}
+
+	return res, nil
+}
+
+// Redis returns redis.Store added by Add or AddRedis.
+func (c *Connector) Redis(name ...string) (redis.Store, error) {
+	conn, err := c.Conn(connector.Redis, name...)
+	if err != nil {
+		return nil, err
+	}
+
+	res, ok := conn.(redis.Store)
+	if !ok {
+		return nil, unexpectedType(connector.Redis, name, conn)
+	}
+
+	return res, nil
+}
+
+// RMQ returns rabbit.Broker added by Add or AddRabbitMQ.
+func (c *Connector) RMQ(name ...string) (rabbit.Broker, error) {
+	conn, err := c.Conn(connector.RabbitMQ, name...)
+	if err != nil {
+		return nil, err
+	}
+
+	res, ok := conn.(rabbit.Broker)
+	if !ok {
+		return nil, unexpectedType(connector.RabbitMQ, name, conn)
+	}
+
+	return res, nil
+}
+
+// unexpectedType returns ErrUnexpectedType with the instance id and the type
+// of the connection.
+func unexpectedType(driver string, name []string, conn interface{}) error {
+	return fmt.Errorf("%s: %w: %T", instanceID(driver, name), ErrUnexpectedType, conn)
+}
+
+// Close marks the connector as closed.
+func (c *Connector) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+
+	return nil
+}
+
+// IsClosed reports whether Close is called.
+func (c *Connector) IsClosed() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.closed
+}
+
+// instanceID returns id of the instance as the connector does.
+func instanceID(driver string, name []string) string {
+	if len(name) == 0 || name[0] == "" || name[0] == connector.DefaultInstance {
+		return driver
+	}
+
+	return driver + "." + name[0]
+}
+
+// failer returns error set by Fail from all operations of the fake.
+type failer struct {
+	mu  sync.Mutex
+	err error
+}
+
+// Fail makes operations of the fake return err. Nil err restores them.
+func (f *failer) Fail(err error) {
+	f.mu.Lock()
+	f.err = err
+	f.mu.Unlock()
+}
+
+func (f *failer) failure() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.err
+}
+
+// failureContext returns ctx error if ctx is done or error set by Fail.
+func (f *failer) failureContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return f.failure()
+}
@@ -0,0 +1,211 @@
+package fake_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-pg/pg/v9"
+	"github.com/outdead/goservice/internal/connector"
+	"github.com/outdead/goservice/internal/connector/fake"
+	"github.com/outdead/goservice/internal/utils/driver/clickhouse"
+	"github.com/outdead/goservice/internal/utils/driver/elasticsearch"
+	"github.com/outdead/goservice/internal/utils/driver/postgres"
+	"github.com/outdead/goservice/internal/utils/driver/rabbit"
+	"github.com/outdead/goservice/internal/utils/driver/redis"
+	"github.com/outdead/goservice/internal/utils/errclass"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	_ connector.Connector    = fake.New()
+	_ postgres.Database      = fake.NewPostgres()
+	_ redis.Store            = fake.NewRedis()
+	_ clickhouse.Database    = fake.NewClickhouse()
+	_ elasticsearch.Database = fake.NewElasticsearch()
+	_ rabbit.Broker          = fake.NewRabbitMQ()
+)
+
+type Row struct {
+	ID   string
+	Name string
+}
+
+func (r *Row) TableName() string   { return "rows" }
+func (r *Row) CalculateID() string { return r.ID }
+
+func TestConnector_Accessors(t *testing.T) {
+	conn := fake.New()
+	conn.AddRedis()
+	conn.AddPostgres("analytics")
+	conn.Add(connector.Elasticsearch, "not a database")
+	conn.Add(connector.RabbitMQ, fake.NewRedis(), "events")
+
+	tests := []struct {
+		name string
+		get  func() error
+		err  error
+	}{
+		{"default redis", func() error { _, err := conn.Redis(); return err }, nil},
+		{"default redis by name", func() error { _, err := conn.Redis(connector.DefaultInstance); return err }, nil},
+		{"named postgres", func() error { _, err := conn.PG("analytics"); return err }, nil},
+		{"default postgres", func() error { _, err := conn.PG(); return err }, connector.ErrNotConfigured},
+		{"clickhouse", func() error { _, err := conn.CH(); return err }, connector.ErrNotConfigured},
+		{"unexpected elasticsearch type", func() error { _, err := conn.ELA(); return err }, fake.ErrUnexpectedType},
+		{"unexpected rabbitmq type", func() error { _, err := conn.RMQ("events"); return err }, fake.ErrUnexpectedType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.get(); !errors.Is(err, tt.err) {
+				t.Errorf("expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestConnector_CheckConnections(t *testing.T) {
+	conn := fake.New()
+	conn.AddPostgres()
+	conn.AddRedis("cache")
+	conn.Lose(connector.Redis, "cache")
+
+	report, err := conn.CheckConnections(0)
+
+	assert.Equal(t, errclass.Recoverable, errclass.Of(err))
+	if assert.Len(t, report, 2) {
+		assert.Equal(t, "postgres", report[0].Name)
+		assert.True(t, report[0].OK)
+		assert.Equal(t, "redis.cache", report[1].Name)
+		assert.False(t, report[1].OK)
+	}
+
+	conn.Restore(connector.Redis, "cache")
+
+	_, err = conn.CheckConnections(0)
+	assert.NoError(t, err)
+}
+
+func TestRedis(t *testing.T) {
+	conn := fake.New()
+	store := conn.AddRedis()
+
+	assert.NoError(t, store.Set("key", 10))
+
+	value, err := store.Get("key")
+	assert.NoError(t, err)
+	assert.Equal(t, "10", value)
+
+	assert.NoError(t, store.Del("key"))
+
+	_, err = store.Get("key")
+	assert.True(t, errors.Is(err, redis.ErrNoRows))
+	assert.True(t, conn.IsErrNotFound(err))
+
+	failure := errors.New("failure")
+	store.Fail(failure)
+	assert.Equal(t, failure, store.Set("key", "value"))
+	assert.Empty(t, store.Data())
+}
+
+func TestClickhouse(t *testing.T) {
+	db := fake.NewClickhouse()
+
+	assert.NoError(t, db.MultiInsert("INSERT INTO rows", [][]interface{}{{1, "a"}, {2, "b"}}))
+	assert.Equal(t, []fake.Insert{{Query: "INSERT INTO rows", Rows: [][]interface{}{{1, "a"}, {2, "b"}}}}, db.Inserts())
+}
+
+func TestPostgres(t *testing.T) {
+	db := fake.NewPostgres()
+
+	var name string
+
+	_, err := db.QueryOne(pg.Scan(&name), "SELECT name FROM rows WHERE id = ?", 1)
+	assert.True(t, db.IsErrNoRows(err), "expected no rows, got %v", err)
+
+	db.Handle(func(q fake.Query) (pg.Result, error) {
+		name = "a"
+
+		return fake.Result{Returned: 1}, nil
+	})
+
+	_, err = db.QueryOne(pg.Scan(&name), "SELECT name FROM rows WHERE id = ?", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "a", name)
+
+	_, err = db.Exec("DELETE FROM rows")
+	assert.NoError(t, err)
+
+	assert.Equal(t, []fake.Query{
+		{Model: pg.Scan(&name), Query: "SELECT name FROM rows WHERE id = ?", Params: []interface{}{1}},
+		{Model: pg.Scan(&name), Query: "SELECT name FROM rows WHERE id = ?", Params: []interface{}{1}},
+		{Query: "DELETE FROM rows"},
+	}, db.Queries())
+}
+
+func TestElasticsearch(t *testing.T) {
+	es := fake.NewElasticsearch()
+
+	assert.NoError(t, es.MultiInsert([]elasticsearch.Model{&Row{ID: "1", Name: "a"}, &Row{ID: "1", Name: "b"}}))
+	assert.Len(t, es.Documents(), 2)
+
+	doc, ok := es.Document("rows", "1")
+	assert.True(t, ok)
+	assert.Equal(t, &Row{ID: "1", Name: "b"}, doc)
+}
+
+func TestRabbitMQ(t *testing.T) {
+	broker := fake.NewRabbitMQ()
+	broker.Bind("outcome", "income")
+
+	assert.NoError(t, broker.Publish("outcome", amqp.Publishing{Body: []byte("message")}))
+	assert.Len(t, broker.Published("outcome"), 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	deliveries, err := broker.Consume(ctx, "income")
+	assert.NoError(t, err)
+
+	delivery := <-deliveries
+	assert.Equal(t, []byte("message"), delivery.Body)
+	assert.NoError(t, delivery.Ack(false))
+	assert.Equal(t, []uint64{delivery.DeliveryTag}, broker.Acked())
+}
+
+func TestRabbitMQ_Consume_Canceled(t *testing.T) {
+	broker := fake.NewRabbitMQ()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	stopped, err := broker.Consume(ctx, "income")
+	assert.NoError(t, err)
+
+	cancel()
+
+	_, ok := <-stopped
+	assert.False(t, ok, "deliveries channel is not closed")
+
+	// Messages are kept in the queue for the next consumer.
+	assert.NoError(t, broker.Deliver("income", amqp.Publishing{Body: []byte("message")}))
+
+	deliveries, err := broker.Consume(context.Background(), "income")
+	assert.NoError(t, err)
+
+	delivery := <-deliveries
+	assert.Equal(t, []byte("message"), delivery.Body)
+}
+
+func TestContextCanceled(t *testing.T) {
+	conn := fake.New()
+	store := conn.AddRedis()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := conn.CheckConnectionsContext(ctx)
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.True(t, errors.Is(store.SetContext(ctx, "key", "value"), context.Canceled))
+	assert.Empty(t, store.Data())
+}
@@ -0,0 +1,70 @@
+package fake
+
+import (
+	"context"
+	"sync"
+
+	"github.com/olivere/elastic"
+	"github.com/outdead/goservice/internal/utils/driver/elasticsearch"
+)
+
+// Elasticsearch is in-memory elasticsearch.Database which records inserted
+// documents.
+type Elasticsearch struct {
+	failer
+
+	mu   sync.Mutex
+	docs []elasticsearch.Model
+}
+
+// NewElasticsearch creates new Elasticsearch.
+func NewElasticsearch() *Elasticsearch {
+	return new(Elasticsearch)
+}
+
+// MultiInsert records the documents.
+func (client *Elasticsearch) MultiInsert(rows []elasticsearch.Model) error {
+	return client.MultiInsertContext(context.Background(), rows)
+}
+
+// MultiInsertContext records the documents or returns ctx error if ctx is
+// done.
+func (client *Elasticsearch) MultiInsertContext(ctx context.Context, rows []elasticsearch.Model) error {
+	if err := client.failureContext(ctx); err != nil {
+		return err
+	}
+
+	client.mu.Lock()
+	client.docs = append(client.docs, rows...)
+	client.mu.Unlock()
+
+	return nil
+}
+
+// Documents returns inserted documents in order of inserts.
+func (client *Elasticsearch) Documents() []elasticsearch.Model {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	return append([]elasticsearch.Model(nil), client.docs...)
+}
+
+// Document returns the last inserted document by table name and id as
+// Elasticsearch replaces documents with the same id.
+func (client *Elasticsearch) Document(table, id string) (elasticsearch.Model, bool) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	for i := len(client.docs) - 1; i >= 0; i-- {
+		if client.docs[i].TableName() == table && client.docs[i].CalculateID() == id {
+			return client.docs[i], true
+		}
+	}
+
+	return nil, false
+}
+
+// IsErrNotFound returns true if error is elastic not found error.
+func (client *Elasticsearch) IsErrNotFound(err error) bool {
+	return elastic.IsNotFound(err)
+}
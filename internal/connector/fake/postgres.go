@@ -0,0 +1,159 @@
+package fake
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-pg/pg/v9"
+	"github.com/go-pg/pg/v9/orm"
+)
+
+// Query contains the query and its params executed on Postgres. Model is nil
+// for Exec.
+type Query struct {
+	Model  interface{}
+	Query  interface{}
+	Params []interface{}
+}
+
+// QueryFunc handles queries executed on Postgres, e.g. fills the model with
+// expected rows and returns their number.
+type QueryFunc func(q Query) (pg.Result, error)
+
+// Result is pg.Result returned by QueryFunc.
+type Result struct {
+	Affected int
+	Returned int
+}
+
+// Model returns nil.
+func (r Result) Model() orm.Model {
+	return nil
+}
+
+// RowsAffected returns number of affected rows.
+func (r Result) RowsAffected() int {
+	return r.Affected
+}
+
+// RowsReturned returns number of returned rows.
+func (r Result) RowsReturned() int {
+	return r.Returned
+}
+
+// Postgres is in-memory postgres.Database which records queries. It has no
+// query engine, results are returned by QueryFunc set by Handle.
+type Postgres struct {
+	failer
+
+	mu      sync.Mutex
+	handler QueryFunc
+	queries []Query
+}
+
+// NewPostgres creates new Postgres.
+func NewPostgres() *Postgres {
+	return new(Postgres)
+}
+
+// Handle sets function which handles the next queries. Without handler
+// queries return empty result, so QueryOne returns pg.ErrNoRows.
+func (db *Postgres) Handle(fn QueryFunc) {
+	db.mu.Lock()
+	db.handler = fn
+	db.mu.Unlock()
+}
+
+// Queries returns executed queries.
+func (db *Postgres) Queries() []Query {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return append([]Query(nil), db.queries...)
+}
+
+// Exec records the query and returns result of the handler.
+func (db *Postgres) Exec(query interface{}, params ...interface{}) (pg.Result, error) {
+	return db.ExecContext(context.Background(), query, params...)
+}
+
+// ExecContext executes the query as Exec does or returns ctx error if ctx is
+// done.
+func (db *Postgres) ExecContext(ctx context.Context, query interface{}, params ...interface{}) (pg.Result, error) {
+	return db.query(ctx, Query{Query: query, Params: params})
+}
+
+// Query records the query and returns result of the handler.
+func (db *Postgres) Query(model, query interface{}, params ...interface{}) (pg.Result, error) {
+	return db.QueryContext(context.Background(), model, query, params...)
+}
+
+// QueryContext executes the query as Query does or returns ctx error if ctx
+// is done.
+func (db *Postgres) QueryContext(ctx context.Context, model, query interface{}, params ...interface{}) (pg.Result, error) {
+	return db.query(ctx, Query{Model: model, Query: query, Params: params})
+}
+
+// QueryOne records the query and returns result of the handler. As pg.DB
+// does, it returns pg.ErrNoRows or pg.ErrMultiRows if the handler returned
+// not exactly one row.
+func (db *Postgres) QueryOne(model, query interface{}, params ...interface{}) (pg.Result, error) {
+	return db.QueryOneContext(context.Background(), model, query, params...)
+}
+
+// QueryOneContext executes the query as QueryOne does or returns ctx error if
+// ctx is done.
+func (db *Postgres) QueryOneContext(ctx context.Context, model, query interface{}, params ...interface{}) (pg.Result, error) {
+	res, err := db.query(ctx, Query{Model: model, Query: query, Params: params})
+	if err != nil {
+		return res, err
+	}
+
+	switch n := res.RowsReturned(); {
+	case n == 0:
+		return res, pg.ErrNoRows
+	case n > 1:
+		return res, pg.ErrMultiRows
+	}
+
+	return res, nil
+}
+
+// GetServerTime returns local time.
+func (db *Postgres) GetServerTime() (time.Time, error) {
+	return db.GetServerTimeContext(context.Background())
+}
+
+// GetServerTimeContext returns local time or ctx error if ctx is done.
+func (db *Postgres) GetServerTimeContext(ctx context.Context) (time.Time, error) {
+	if err := db.failureContext(ctx); err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Now(), nil
+}
+
+// IsErrNoRows returns true if err is pg.ErrNoRows.
+func (db *Postgres) IsErrNoRows(err error) bool {
+	return errors.Is(err, pg.ErrNoRows)
+}
+
+// query records the query and calls the handler.
+func (db *Postgres) query(ctx context.Context, q Query) (pg.Result, error) {
+	if err := db.failureContext(ctx); err != nil {
+		return nil, err
+	}
+
+	db.mu.Lock()
+	db.queries = append(db.queries, q)
+	handler := db.handler
+	db.mu.Unlock()
+
+	if handler == nil {
+		return Result{}, nil
+	}
+
+	return handler(q)
+}
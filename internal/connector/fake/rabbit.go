@@ -0,0 +1,212 @@
+package fake
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/streadway/amqp"
+)
+
+// DeliveryBuffer is the capacity of the consumer deliveries channel.
+const DeliveryBuffer = 100
+
+// ErrDeliveryBufferFull is returned when the message is delivered to the
+// consumer which does not read its deliveries.
+var ErrDeliveryBufferFull = errors.New("consumer delivery buffer is full")
+
+// RabbitMQ is in-memory rabbit.Broker. Published messages are recorded and
+// delivered to the consumers bound to the publisher by Bind. Deliveries are
+// acknowledged to the broker, so acks can be asserted too.
+type RabbitMQ struct {
+	failer
+
+	mu        sync.Mutex
+	tag       uint64
+	published map[string][]amqp.Publishing
+	bindings  map[string][]string
+	consumers map[string]chan amqp.Delivery
+	acked     []uint64
+	rejected  []uint64
+}
+
+// NewRabbitMQ creates new RabbitMQ without bindings.
+func NewRabbitMQ() *RabbitMQ {
+	return &RabbitMQ{
+		published: make(map[string][]amqp.Publishing),
+		bindings:  make(map[string][]string),
+		consumers: make(map[string]chan amqp.Delivery),
+	}
+}
+
+// Bind routes messages of the publisher to the consumer.
+func (b *RabbitMQ) Bind(publisher, consumer string) {
+	b.mu.Lock()
+	b.bindings[publisher] = append(b.bindings[publisher], consumer)
+	b.mu.Unlock()
+}
+
+// Publish records the message and delivers it to the bound consumers.
+func (b *RabbitMQ) Publish(publisher string, msg amqp.Publishing) error {
+	return b.PublishContext(context.Background(), publisher, msg)
+}
+
+// PublishContext publishes the message as Publish does or returns ctx error
+// if ctx is done.
+func (b *RabbitMQ) PublishContext(ctx context.Context, publisher string, msg amqp.Publishing) error {
+	if err := b.failureContext(ctx); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.published[publisher] = append(b.published[publisher], msg)
+
+	for _, consumer := range b.bindings[publisher] {
+		if err := b.deliver(consumer, msg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Consume starts new consumer of the queue by consumer name and returns its
+// deliveries. Messages delivered before the call are kept in the queue and
+// consumers of the same queue share them. The consumer is stopped and its
+// deliveries channel is closed when ctx is done, the message taken from the
+// queue but not received is returned to the queue.
+func (b *RabbitMQ) Consume(ctx context.Context, consumer string) (<-chan amqp.Delivery, error) {
+	if err := b.failureContext(ctx); err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	queue := b.deliveries(consumer)
+	b.mu.Unlock()
+
+	deliveries := make(chan amqp.Delivery)
+
+	go func() {
+		defer close(deliveries)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case delivery := <-queue:
+				select {
+				case deliveries <- delivery:
+				case <-ctx.Done():
+					queue <- delivery
+
+					return
+				}
+			}
+		}
+	}()
+
+	return deliveries, nil
+}
+
+// Deliver delivers the message to the consumer directly as if it was
+// published by another service.
+func (b *RabbitMQ) Deliver(consumer string, msg amqp.Publishing) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.deliver(consumer, msg)
+}
+
+// Published returns messages published by the publisher.
+func (b *RabbitMQ) Published(publisher string) []amqp.Publishing {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]amqp.Publishing(nil), b.published[publisher]...)
+}
+
+// Acked returns tags of the acknowledged deliveries.
+func (b *RabbitMQ) Acked() []uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]uint64(nil), b.acked...)
+}
+
+// Rejected returns tags of the negatively acknowledged and rejected
+// deliveries.
+func (b *RabbitMQ) Rejected() []uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]uint64(nil), b.rejected...)
+}
+
+// Ack implements amqp.Acknowledger. Multiple acks are recorded by the tag.
+func (b *RabbitMQ) Ack(tag uint64, _ bool) error {
+	b.mu.Lock()
+	b.acked = append(b.acked, tag)
+	b.mu.Unlock()
+
+	return nil
+}
+
+// Nack implements amqp.Acknowledger. Requeue is not supported.
+func (b *RabbitMQ) Nack(tag uint64, _ bool, _ bool) error {
+	return b.Reject(tag, false)
+}
+
+// Reject implements amqp.Acknowledger. Requeue is not supported.
+func (b *RabbitMQ) Reject(tag uint64, _ bool) error {
+	b.mu.Lock()
+	b.rejected = append(b.rejected, tag)
+	b.mu.Unlock()
+
+	return nil
+}
+
+// deliveries returns queue of the consumer. It must be called under the
+// lock.
+func (b *RabbitMQ) deliveries(consumer string) chan amqp.Delivery {
+	ch, ok := b.consumers[consumer]
+	if !ok {
+		ch = make(chan amqp.Delivery, DeliveryBuffer)
+		b.consumers[consumer] = ch
+	}
+
+	return ch
+}
+
+// deliver sends the message to the consumer. It must be called under the
+// lock.
+func (b *RabbitMQ) deliver(consumer string, msg amqp.Publishing) error {
+	b.tag++
+
+	delivery := amqp.Delivery{
+		Acknowledger:    b,
+		DeliveryTag:     b.tag,
+		Headers:         msg.Headers,
+		ContentType:     msg.ContentType,
+		ContentEncoding: msg.ContentEncoding,
+		DeliveryMode:    msg.DeliveryMode,
+		Priority:        msg.Priority,
+		CorrelationId:   msg.CorrelationId,
+		ReplyTo:         msg.ReplyTo,
+		Expiration:      msg.Expiration,
+		MessageId:       msg.MessageId,
+		Timestamp:       msg.Timestamp,
+		Type:            msg.Type,
+		UserId:          msg.UserId,
+		AppId:           msg.AppId,
+		Body:            msg.Body,
+	}
+
+	select {
+	case b.deliveries(consumer) <- delivery:
+		return nil
+	default:
+		return ErrDeliveryBufferFull
+	}
+}
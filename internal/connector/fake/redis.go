@@ -0,0 +1,132 @@
+package fake
+
+import (
+	"context"
+	"encoding"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/outdead/goservice/internal/utils/driver/redis"
+)
+
+// Redis is in-memory redis.Store. Keys do not expire.
+type Redis struct {
+	failer
+
+	mu   sync.Mutex
+	data map[string]string
+}
+
+// NewRedis creates new empty Redis.
+func NewRedis() *Redis {
+	return &Redis{data: make(map[string]string)}
+}
+
+// Set sets value by key. Value is formatted as go-redis does for the basic
+// types.
+func (client *Redis) Set(key string, data interface{}) error {
+	return client.SetContext(context.Background(), key, data)
+}
+
+// SetContext sets value by key or returns ctx error if ctx is done.
+func (client *Redis) SetContext(ctx context.Context, key string, data interface{}) error {
+	if err := client.failureContext(ctx); err != nil {
+		return err
+	}
+
+	value, err := format(data)
+	if err != nil {
+		return err
+	}
+
+	client.mu.Lock()
+	client.data[key] = value
+	client.mu.Unlock()
+
+	return nil
+}
+
+// Get gets value by key. It returns redis.ErrNoRows if the key is not set.
+func (client *Redis) Get(key string) (string, error) {
+	return client.GetContext(context.Background(), key)
+}
+
+// GetContext gets value by key or returns ctx error if ctx is done.
+func (client *Redis) GetContext(ctx context.Context, key string) (string, error) {
+	if err := client.failureContext(ctx); err != nil {
+		return "", err
+	}
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	value, ok := client.data[key]
+	if !ok {
+		return "", redis.ErrNoRows
+	}
+
+	return value, nil
+}
+
+// Del deletes value by key.
+func (client *Redis) Del(key string) error {
+	return client.DelContext(context.Background(), key)
+}
+
+// DelContext deletes value by key or returns ctx error if ctx is done.
+func (client *Redis) DelContext(ctx context.Context, key string) error {
+	if err := client.failureContext(ctx); err != nil {
+		return err
+	}
+
+	client.mu.Lock()
+	delete(client.data, key)
+	client.mu.Unlock()
+
+	return nil
+}
+
+// IsErrNoRows returns true if err is redis.ErrNoRows.
+func (client *Redis) IsErrNoRows(err error) bool {
+	return errors.Is(err, redis.ErrNoRows)
+}
+
+// Data returns copy of the stored values by keys.
+func (client *Redis) Data() map[string]string {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	data := make(map[string]string, len(client.data))
+	for key, value := range client.data {
+		data[key] = value
+	}
+
+	return data
+}
+
+func format(data interface{}) (string, error) {
+	switch v := data.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case bool:
+		if v {
+			return "1", nil
+		}
+
+		return "0", nil
+	case encoding.BinaryMarshaler:
+		b, err := v.MarshalBinary()
+		if err != nil {
+			return "", err
+		}
+
+		return string(b), nil
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
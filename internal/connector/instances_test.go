@@ -0,0 +1,139 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/outdead/goservice/internal/utils/driver/postgres"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubDB is the PostgreSQL connection which records its config. Database
+// methods are not called by the tests.
+type stubDB struct {
+	postgres.Database
+	config *postgres.Config
+	closed bool
+}
+
+// stubPostgres replaces connection functions of the built-in Postgres driver
+// by the ones which do not connect to the database.
+func stubPostgres(t *testing.T) {
+	t.Helper()
+
+	d, _ := Lookup(Postgres)
+	orig := *d
+
+	d.Connect = func(cfg DriverConfig) (interface{}, error) {
+		return &stubDB{config: cfg.(*postgres.Config)}, nil
+	}
+	d.IsConnected = func(ctx context.Context, conn interface{}) bool {
+		return !conn.(*stubDB).closed
+	}
+	d.Close = func(conn interface{}) error {
+		conn.(*stubDB).closed = true
+
+		return nil
+	}
+
+	t.Cleanup(func() { *d = orig })
+}
+
+func pgConfig(addr string) *postgres.Config {
+	return &postgres.Config{Addr: addr, Database: "goservice", User: "postgres", Password: "postgres"}
+}
+
+func TestConnector_Instances(t *testing.T) {
+	stubPostgres(t)
+
+	cfg := Config{Postgres: pgConfig("main:5432")}
+	cfg.Instances.Postgres = map[string]*postgres.Config{"analytics": pgConfig("analytics:5432")}
+
+	conn, err := New(&cfg)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	defer conn.Close()
+
+	tests := []struct {
+		name string
+		get  func() (postgres.Database, error)
+		addr string
+		err  error
+	}{
+		{"default", func() (postgres.Database, error) { return conn.PG() }, "main:5432", nil},
+		{"default alias", func() (postgres.Database, error) { return conn.PG(DefaultInstance) }, "main:5432", nil},
+		{"named", func() (postgres.Database, error) { return conn.PG("analytics") }, "analytics:5432", nil},
+		{"unknown", func() (postgres.Database, error) { return conn.PG("reports") }, "", ErrNotConfigured},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := tt.get()
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+
+			if tt.err == nil {
+				assert.Equal(t, tt.addr, db.(*stubDB).config.Addr)
+			}
+		})
+	}
+
+	report, err := conn.CheckConnections(time.Second)
+	assert.NoError(t, err)
+
+	if assert.Len(t, report, 2) {
+		assert.Equal(t, "postgres", report[0].Name)
+		assert.Equal(t, "postgres.analytics", report[1].Name)
+	}
+}
+
+func TestConnector_Reload_Instances(t *testing.T) {
+	stubPostgres(t)
+
+	cfg := Config{Postgres: pgConfig("main:5432")}
+	cfg.Instances.Postgres = map[string]*postgres.Config{
+		"analytics": pgConfig("analytics:5432"),
+		"reports":   pgConfig("reports:5432"),
+	}
+
+	conn, err := New(&cfg)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	defer conn.Close()
+
+	main, _ := conn.PG()
+	analytics, _ := conn.PG("analytics")
+	reports, _ := conn.PG("reports")
+
+	// Default instance is kept, analytics is changed and reports is removed.
+	next := Config{Postgres: pgConfig("main:5432")}
+	next.Instances.Postgres = map[string]*postgres.Config{"analytics": pgConfig("analytics:6432")}
+
+	if !assert.NoError(t, conn.Reload(&next)) {
+		return
+	}
+
+	db, err := conn.PG()
+	assert.NoError(t, err)
+	assert.Same(t, main, db, "unchanged instance is reconnected")
+
+	db, err = conn.PG("analytics")
+	assert.NoError(t, err)
+	assert.NotSame(t, analytics, db, "changed instance is not reconnected")
+	assert.Equal(t, "analytics:6432", db.(*stubDB).config.Addr)
+
+	_, err = conn.PG("reports")
+	assert.True(t, errors.Is(err, ErrNotConfigured), "removed instance is available: %v", err)
+
+	// Zero reconnect.close_delay closes replaced connections at once.
+	assert.False(t, main.(*stubDB).closed)
+	assert.True(t, analytics.(*stubDB).closed)
+	assert.True(t, reports.(*stubDB).closed)
+}
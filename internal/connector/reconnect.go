@@ -0,0 +1,177 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/outdead/goservice/internal/utils/backoff"
+	"github.com/outdead/goservice/internal/utils/multierror"
+)
+
+// handleOutages tracks outages of the checked connections, starts
+// reconnections of the lost ones and returns errors of the connections which
+// have been lost longer than reconnect.outage_window.
+func (conn *connector) handleOutages(report Report) error {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	cfg := &conn.config.Reconnect
+	errs := multierror.New()
+	now := time.Now()
+
+	for _, status := range report {
+		if status.OK {
+			delete(conn.outages, status.Name)
+
+			continue
+		}
+
+		since, ok := conn.outages[status.Name]
+		if !ok {
+			since = now
+			conn.outages[status.Name] = since
+		}
+
+		if cfg.Enabled && conn.isReconnectable(status.Name) && !conn.reconnecting[status.Name] {
+			conn.reconnecting[status.Name] = true
+
+			go conn.reconnectLoop(status.Name, backoff.New(&cfg.Backoff))
+		}
+
+		if now.Sub(since) >= cfg.OutageWindow {
+			errs.Append(status.Err)
+		}
+	}
+
+	if errs.Len() != 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// isReconnectable reports whether the connection is reconnected by the
+// connector. RabbitMQ and Elasticsearch clients restore connections by
+// themselves. It must be called under the lock.
+func (conn *connector) isReconnectable(name string) bool {
+	s, ok := conn.config.section(name)
+
+	return ok && s.driver.Reconnectable
+}
+
+// reconnectLoop reconnects to the database with backoff delays until the
+// connection is restored or the connector is closed.
+func (conn *connector) reconnectLoop(name string, b *backoff.Backoff) {
+	defer func() {
+		conn.mu.Lock()
+		delete(conn.reconnecting, name)
+		conn.mu.Unlock()
+	}()
+
+	for {
+		delay := b.Next()
+
+		select {
+		case <-conn.ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		// Connection can be restored by itself or replaced by reload.
+		if conn.isConnected(name) {
+			conn.restored(name)
+
+			return
+		}
+
+		if err := conn.reconnect(name); err != nil {
+			conn.logger.WithError(err).Warnf("reconnect %s failed (attempt %d)", name, b.Attempt())
+
+			continue
+		}
+
+		conn.logger.Infof("reconnect %s success", name)
+		conn.restored(name)
+
+		return
+	}
+}
+
+// isClosed reports whether Close is called.
+func (conn *connector) isClosed() bool {
+	return conn.ctx.Err() != nil
+}
+
+// restored clears outage of the connection.
+func (conn *connector) restored(name string) {
+	conn.mu.Lock()
+	delete(conn.outages, name)
+	conn.mu.Unlock()
+}
+
+// isConnected checks current connection by name. The check is aborted by
+// Close or after DefaultCheckTimeout.
+func (conn *connector) isConnected(name string) bool {
+	ctx, cancel := context.WithTimeout(conn.ctx, DefaultCheckTimeout)
+	defer cancel()
+
+	for _, c := range conn.checks() {
+		if c.name == name {
+			return c.isConnected(ctx)
+		}
+	}
+
+	// Connection is removed by reload.
+	return true
+}
+
+// reconnect establishes new connection by name and replaces the current one.
+// If config is reloaded while connecting the new connection is discarded and
+// the current one is checked again on the next attempt.
+func (conn *connector) reconnect(name string) error {
+	conn.mu.RLock()
+	cfg := conn.config
+	conn.mu.RUnlock()
+
+	s, ok := cfg.section(name)
+	if !ok {
+		return fmt.Errorf("%s: %w", name, ErrNotConfigured)
+	}
+
+	c, err := s.driver.Connect(s.config)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	fresh := &instance{driver: s.driver, conn: c}
+
+	conn.mu.Lock()
+
+	if conn.isClosed() || conn.config != cfg {
+		err := ErrConfigReloaded
+		if conn.isClosed() {
+			err = ErrClosed
+		}
+
+		conn.mu.Unlock()
+
+		// Close error of the unused connection does not matter.
+		_ = fresh.Close()
+
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	old := conn.instances[name]
+	conn.instances[name] = fresh
+
+	conn.mu.Unlock()
+
+	// Lost connection can still be used by queries started before the
+	// replacement.
+	if old != nil {
+		conn.retire(map[string]*instance{name: old})
+	}
+
+	return nil
+}
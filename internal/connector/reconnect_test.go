@@ -0,0 +1,87 @@
+package connector_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/outdead/goservice/internal/connector"
+	"github.com/outdead/goservice/internal/utils/backoff"
+	"github.com/stretchr/testify/assert"
+)
+
+func reconnectConfig(closeDelay time.Duration) connector.ReconnectConfig {
+	return connector.ReconnectConfig{
+		Enabled:    true,
+		Backoff:    backoff.Config{InitialInterval: 10 * time.Millisecond, MaxInterval: 10 * time.Millisecond, Multiplier: 1},
+		CloseDelay: closeDelay,
+	}
+}
+
+func TestConnector_Reconnect_KeepsRetiredConnection(t *testing.T) {
+	addr, _ := NewServer()
+
+	cfg := connector.Config{
+		Drivers:   connector.DriverConfigs{alpha: &TestConfig{Addr: addr}},
+		Reconnect: reconnectConfig(300 * time.Millisecond),
+	}
+
+	conn, err := connector.New(&cfg)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	defer conn.Close()
+
+	// Handle is held by the query started before the connection is lost.
+	held, _ := conn.Conn(alpha)
+	held.(*Conn).Break()
+
+	report, err := conn.CheckConnections(time.Second)
+	assert.Error(t, err)
+	assert.False(t, report.OK())
+
+	assert.Eventually(t, func() bool {
+		c, _ := conn.Conn(alpha)
+
+		return c != held
+	}, time.Second, 5*time.Millisecond, "connection is not replaced")
+
+	assert.False(t, held.(*Conn).Closed(), "retired connection is closed before close_delay")
+
+	assert.Eventually(t, held.(*Conn).Closed, time.Second, 5*time.Millisecond, "retired connection is not closed after close_delay")
+
+	report, err = conn.CheckConnections(time.Second)
+	assert.NoError(t, err)
+	assert.True(t, report.OK())
+}
+
+func TestConnector_Reload_KeepsRetiredConnection(t *testing.T) {
+	addrA, _ := NewServer()
+	addrB, _ := NewServer()
+
+	cfg := connector.Config{
+		Drivers:   connector.DriverConfigs{alpha: &TestConfig{Addr: addrA}},
+		Reconnect: reconnectConfig(time.Hour),
+	}
+
+	conn, err := connector.New(&cfg)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	held, _ := conn.Conn(alpha)
+
+	next := cfg
+	next.Drivers = connector.DriverConfigs{alpha: &TestConfig{Addr: addrB}}
+
+	assert.NoError(t, conn.Reload(&next))
+
+	c, _ := conn.Conn(alpha)
+	assert.NotSame(t, held, c)
+	assert.False(t, held.(*Conn).Closed(), "replaced connection is closed before close_delay")
+
+	// Close does not wait for close_delay.
+	assert.NoError(t, conn.Close())
+	assert.True(t, held.(*Conn).Closed())
+	assert.True(t, c.(*Conn).Closed())
+}
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS example (
+    id UInt64,
+    name String,
+    created_at DateTime
+) ENGINE = MergeTree ORDER BY id;
@@ -0,0 +1,147 @@
+// Package migrations contains SQL migrations embedded in the binary. Files
+// are named <version>_<name>.up.sql and <version>_<name>.down.sql and are
+// placed to the directory of the database.
+package migrations
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+
+	"github.com/outdead/goservice/internal/connector"
+	"github.com/outdead/goservice/internal/utils/migrate"
+)
+
+// Databases which have migrations.
+const (
+	Postgres   = "postgres"
+	Clickhouse = "clickhouse"
+)
+
+// ErrUnknownDatabase is returned when migrations are requested for database
+// which has no migrations.
+var ErrUnknownDatabase = errors.New("database must be postgres or clickhouse")
+
+//go:embed postgres/*.sql clickhouse/*.sql
+var files embed.FS
+
+// Connections returns config of the connections required by migrations of
+// the databases. If names are set, only the listed databases are included.
+func Connections(cfg *connector.Config, names ...string) (*connector.Config, error) {
+	if err := validateNames(names); err != nil {
+		return nil, err
+	}
+
+	conns := connector.Config{}
+
+	if isSelected(Postgres, names) {
+		conns.Postgres = cfg.Postgres
+	}
+
+	if isSelected(Clickhouse, names) {
+		conns.Clickhouse = cfg.Clickhouse
+	}
+
+	return &conns, nil
+}
+
+// NewMigrators returns migrators of the databases configured in conn. If
+// names are set, only the listed databases are returned. Connections which
+// do not expose their drivers, e.g. fakes, return migrate.ErrUnsupported. Migrations of the
+// replicas are serialized by Postgres advisory lock. ClickHouse has no locks
+// and is locked by Postgres if it is configured too, otherwise replicas
+// started at the same time can apply the same ClickHouse migration.
+func NewMigrators(conn connector.Connector, names ...string) ([]*migrate.Migrator, error) {
+	if err := validateNames(names); err != nil {
+		return nil, err
+	}
+
+	var (
+		migrators []*migrate.Migrator
+		locker    migrate.Locker
+	)
+
+	if isSelected(Postgres, names) {
+		db, err := conn.PG()
+
+		switch {
+		case err == nil:
+			pdb, ok := db.(migrate.PostgresDB)
+			if !ok {
+				return nil, fmt.Errorf("%s: %w", Postgres, migrate.ErrUnsupported)
+			}
+
+			driver := migrate.NewPostgresDriver(pdb)
+
+			m, err := newMigrator(Postgres, driver)
+			if err != nil {
+				return nil, err
+			}
+
+			migrators = append(migrators, m)
+			locker = driver
+		case !errors.Is(err, connector.ErrNotConfigured) || len(names) != 0:
+			return nil, err
+		}
+	}
+
+	if isSelected(Clickhouse, names) {
+		db, err := conn.CH()
+
+		switch {
+		case err == nil:
+			cdb, ok := db.(migrate.ClickhouseDB)
+			if !ok {
+				return nil, fmt.Errorf("%s: %w", Clickhouse, migrate.ErrUnsupported)
+			}
+
+			m, err := newMigrator(Clickhouse, migrate.NewClickhouseDriver(cdb))
+			if err != nil {
+				return nil, err
+			}
+
+			if locker != nil {
+				m.SetLocker(locker)
+			}
+
+			migrators = append(migrators, m)
+		case !errors.Is(err, connector.ErrNotConfigured) || len(names) != 0:
+			return nil, err
+		}
+	}
+
+	return migrators, nil
+}
+
+func newMigrator(name string, driver migrate.Driver) (*migrate.Migrator, error) {
+	list, err := migrate.Load(files, name)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	return migrate.NewMigrator(name, driver, list), nil
+}
+
+func validateNames(names []string) error {
+	for _, name := range names {
+		if name != Postgres && name != Clickhouse {
+			return fmt.Errorf("%w: %s", ErrUnknownDatabase, name)
+		}
+	}
+
+	return nil
+}
+
+func isSelected(name string, names []string) bool {
+	if len(names) == 0 {
+		return true
+	}
+
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS example (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
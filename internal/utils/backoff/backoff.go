@@ -0,0 +1,137 @@
+// Package backoff implements exponential backoff for repeated attempts of
+// failed operations.
+package backoff
+
+import (
+	"errors"
+	"time"
+
+	"github.com/outdead/goservice/internal/utils/multierror"
+)
+
+// Default backoff settings are used when the corresponding config value is
+// not set.
+const (
+	DefaultInitialInterval = time.Second
+	DefaultMaxInterval     = time.Minute
+	DefaultMultiplier      = 2
+)
+
+// Config validation errors.
+var (
+	ErrInvalidInitialInterval = errors.New("initial_interval must be positive number or zero")
+	ErrInvalidMaxInterval     = errors.New("max_interval must be positive number or zero")
+	ErrInvalidMultiplier      = errors.New("multiplier must be greater than or equal to 1 or zero")
+)
+
+// Config contains exponential backoff settings. Zero values are replaced by
+// defaults.
+type Config struct {
+	InitialInterval time.Duration `yaml:"initial_interval" json:"initial_interval"`
+	MaxInterval     time.Duration `yaml:"max_interval" json:"max_interval"`
+	Multiplier      float64       `yaml:"multiplier" json:"multiplier"`
+}
+
+// SetDefaults sets default values of the fields which are not set.
+func (cfg *Config) SetDefaults() {
+	if cfg.InitialInterval == 0 {
+		cfg.InitialInterval = DefaultInitialInterval
+	}
+
+	if cfg.MaxInterval == 0 {
+		cfg.MaxInterval = DefaultMaxInterval
+	}
+
+	if cfg.Multiplier == 0 {
+		cfg.Multiplier = DefaultMultiplier
+	}
+}
+
+// Validate checks required fields and validates for allowed values.
+func (cfg *Config) Validate() error {
+	errs := multierror.New()
+
+	if cfg.InitialInterval < 0 {
+		errs.Append(multierror.Field("initial_interval", ErrInvalidInitialInterval))
+	}
+
+	if cfg.MaxInterval < 0 {
+		errs.Append(multierror.Field("max_interval", ErrInvalidMaxInterval))
+	}
+
+	if cfg.Multiplier != 0 && cfg.Multiplier < 1 {
+		errs.Append(multierror.Field("multiplier", ErrInvalidMultiplier))
+	}
+
+	if errs.Len() != 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// Backoff calculates delays between attempts. Each next delay is multiplied
+// by Multiplier until it reaches MaxInterval. Backoff is not safe for
+// concurrent use.
+type Backoff struct {
+	initial    time.Duration
+	max        time.Duration
+	multiplier float64
+
+	attempt int
+	current time.Duration
+}
+
+// New creates and returns new Backoff. Defaults of the config must be set
+// (see Config.SetDefaults).
+func New(cfg *Config) *Backoff {
+	b := Backoff{
+		initial:    cfg.InitialInterval,
+		max:        cfg.MaxInterval,
+		multiplier: cfg.Multiplier,
+	}
+
+	if b.max < b.initial {
+		b.max = b.initial
+	}
+
+	return &b
+}
+
+// Next returns the delay before the next attempt and increases the attempts
+// counter.
+func (b *Backoff) Next() time.Duration {
+	b.attempt++
+
+	if b.current == 0 {
+		b.current = b.initial
+
+		return b.current
+	}
+
+	next := time.Duration(float64(b.current) * b.multiplier)
+	if next > b.max || next <= 0 {
+		// Overflow is also limited by the max interval.
+		next = b.max
+	}
+
+	b.current = next
+
+	return b.current
+}
+
+// Attempt returns the number of Next calls since the last Reset.
+func (b *Backoff) Attempt() int {
+	return b.attempt
+}
+
+// MaxInterval returns the upper limit of delays.
+func (b *Backoff) MaxInterval() time.Duration {
+	return b.max
+}
+
+// Reset resets delays and the attempts counter to initial state.
+func (b *Backoff) Reset() {
+	b.attempt = 0
+	b.current = 0
+}
@@ -0,0 +1,64 @@
+package backoff_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/outdead/goservice/internal/utils/backoff"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  backoff.Config
+		wantErr bool
+	}{
+		{"positive validation", backoff.Config{
+			InitialInterval: time.Second,
+			MaxInterval:     time.Minute,
+			Multiplier:      1.5,
+		}, false},
+		{"empty config", backoff.Config{}, false},
+		{"negative initial_interval", backoff.Config{InitialInterval: -1}, true},
+		{"negative max_interval", backoff.Config{MaxInterval: -1}, true},
+		{"small multiplier", backoff.Config{Multiplier: 0.5}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validation error expected: %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestBackoff_Next(t *testing.T) {
+	b := backoff.New(&backoff.Config{
+		InitialInterval: time.Second,
+		MaxInterval:     5 * time.Second,
+		Multiplier:      2,
+	})
+
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+
+	for i, w := range want {
+		if got := b.Next(); got != w {
+			t.Errorf("attempt %d: delay expected: %s, got %s", i+1, w, got)
+		}
+	}
+
+	if b.Attempt() != len(want) {
+		t.Errorf("attempts expected: %d, got %d", len(want), b.Attempt())
+	}
+
+	b.Reset()
+
+	if got := b.Next(); got != time.Second {
+		t.Errorf("delay after reset expected: %s, got %s", time.Second, got)
+	}
+
+	if b.Attempt() != 1 {
+		t.Errorf("attempts after reset expected: %d, got %d", 1, b.Attempt())
+	}
+}
@@ -0,0 +1,93 @@
+package backoff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/outdead/goservice/internal/utils/multierror"
+)
+
+// ErrInvalidMaxWait is returned when max_wait is negative.
+var ErrInvalidMaxWait = errors.New("max_wait must be positive number or zero")
+
+// RetryConfig contains settings of the attempts repeated with backoff delays
+// during limited time. It is the startup section of the connections config:
+// the first connection is retried while the database is not available yet,
+// e.g. when it is started together with the service.
+type RetryConfig struct {
+	// MaxWait is the time given to the repeated attempts. Zero disables
+	// retries, the first error is returned.
+	MaxWait time.Duration `yaml:"max_wait" json:"max_wait"`
+	Backoff Config        `yaml:"backoff" json:"backoff"`
+}
+
+// SetDefaults sets default values of the fields which are not set.
+func (cfg *RetryConfig) SetDefaults() {
+	cfg.Backoff.SetDefaults()
+}
+
+// Validate checks required fields and validates for allowed values.
+func (cfg *RetryConfig) Validate() error {
+	errs := multierror.New()
+
+	if cfg.MaxWait < 0 {
+		errs.Append(multierror.Field("max_wait", ErrInvalidMaxWait))
+	}
+
+	errs.Append(multierror.Prefix(cfg.Backoff.Validate(), "backoff"))
+
+	if errs.Len() != 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// NotifyFunc is called after each failed attempt with the attempt error,
+// number of failed attempts and delay before the next attempt.
+type NotifyFunc func(err error, attempt int, delay time.Duration)
+
+// Retry calls fn until it succeeds, cfg.MaxWait elapses or ctx is done. The
+// last error is returned if all attempts fail. If ctx is done the ctx error
+// is returned with the last error in the message. notify can be nil.
+func Retry(ctx context.Context, cfg *RetryConfig, fn func() error, notify NotifyFunc) error {
+	err := fn()
+	if err == nil || cfg.MaxWait <= 0 {
+		return err
+	}
+
+	b := New(&cfg.Backoff)
+	deadline := time.Now().Add(cfg.MaxWait)
+
+	for {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return fmt.Errorf("%d attempts in %s: %w", b.Attempt()+1, cfg.MaxWait, err)
+		}
+
+		delay := b.Next()
+		if delay > remaining {
+			delay = remaining
+		}
+
+		if notify != nil {
+			notify(err, b.Attempt(), delay)
+		}
+
+		timer := time.NewTimer(delay)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return fmt.Errorf("%w after %d attempts: %v", ctx.Err(), b.Attempt(), err) //nolint:errorlint // Attempt error is in the message only.
+		case <-timer.C:
+		}
+
+		if err = fn(); err == nil {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package backoff_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/outdead/goservice/internal/utils/backoff"
+)
+
+var errAttempt = errors.New("attempt error")
+
+func TestRetry(t *testing.T) {
+	cfg := backoff.RetryConfig{
+		MaxWait: time.Second,
+		Backoff: backoff.Config{InitialInterval: time.Millisecond, MaxInterval: 5 * time.Millisecond},
+	}
+	cfg.SetDefaults()
+
+	calls, notified := 0, 0
+
+	err := backoff.Retry(context.Background(), &cfg, func() error {
+		calls++
+		if calls < 3 {
+			return errAttempt
+		}
+
+		return nil
+	}, func(err error, attempt int, delay time.Duration) {
+		notified++
+
+		if attempt != notified {
+			t.Errorf("attempt expected: %d, got %d", notified, attempt)
+		}
+	})
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	if calls != 3 || notified != 2 {
+		t.Errorf("expected 3 calls and 2 notifications, got %d and %d", calls, notified)
+	}
+}
+
+func TestRetry_MaxWait(t *testing.T) {
+	cfg := backoff.RetryConfig{
+		MaxWait: 20 * time.Millisecond,
+		Backoff: backoff.Config{InitialInterval: 5 * time.Millisecond},
+	}
+	cfg.SetDefaults()
+
+	start := time.Now()
+
+	err := backoff.Retry(context.Background(), &cfg, func() error { return errAttempt }, nil)
+	if !errors.Is(err, errAttempt) {
+		t.Errorf("expected error %v, got %v", errAttempt, err)
+	}
+
+	if elapsed := time.Since(start); elapsed < cfg.MaxWait {
+		t.Errorf("expected retries during %s, got %s", cfg.MaxWait, elapsed)
+	}
+}
+
+func TestRetry_Disabled(t *testing.T) {
+	calls := 0
+
+	err := backoff.Retry(context.Background(), &backoff.RetryConfig{}, func() error {
+		calls++
+
+		return errAttempt
+	}, nil)
+
+	if !errors.Is(err, errAttempt) || calls != 1 {
+		t.Errorf("expected single failed call, got %d calls and %v", calls, err)
+	}
+}
+
+func TestRetry_Canceled(t *testing.T) {
+	cfg := backoff.RetryConfig{
+		MaxWait: time.Minute,
+		Backoff: backoff.Config{InitialInterval: time.Second},
+	}
+	cfg.SetDefaults()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+
+	err := backoff.Retry(ctx, &cfg, func() error { return errAttempt }, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected error %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	if elapsed := time.Since(start); elapsed >= cfg.Backoff.InitialInterval {
+		t.Errorf("expected retries to stop on ctx done, got %s", elapsed)
+	}
+}
@@ -0,0 +1,203 @@
+package configutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Decoder decodes config files of some format. Decoders are registered by
+// file extensions with RegisterDecoder.
+type Decoder interface {
+	// Decode decodes data to the struct pointed by v and returns generic
+	// representation of the data (string keyed maps, lists and scalars). The
+	// representation is used to merge several files and to find unknown keys.
+	// It is returned even if data cannot be decoded to v.
+	Decode(data []byte, v interface{}) (tree interface{}, err error)
+
+	// Tag returns the struct tag the data keys correspond to: yaml or json.
+	Tag() string
+}
+
+var (
+	decodersMu sync.RWMutex
+	decoders   = map[string]Decoder{
+		".yaml": YAMLDecoder{},
+		".yml":  YAMLDecoder{},
+		".json": JSONDecoder{},
+		".toml": TOMLDecoder{},
+		".env":  EnvDecoder{},
+	}
+)
+
+// RegisterDecoder registers decoder of the files with the extension, e.g.
+// ".hcl". Registered decoder replaces the existing one.
+func RegisterDecoder(ext string, decoder Decoder) {
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+
+	decoders[strings.ToLower(ext)] = decoder
+}
+
+// LookupDecoder returns decoder of the files with the extension.
+func LookupDecoder(ext string) (Decoder, bool) {
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+
+	decoder, ok := decoders[strings.ToLower(ext)]
+
+	return decoder, ok
+}
+
+// Extensions returns sorted extensions of the registered decoders.
+func Extensions() []string {
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+
+	exts := make([]string, 0, len(decoders))
+	for ext := range decoders {
+		exts = append(exts, ext)
+	}
+
+	sort.Strings(exts)
+
+	return exts
+}
+
+// YAMLDecoder decodes yaml files.
+type YAMLDecoder struct{}
+
+// Decode decodes yaml data.
+func (YAMLDecoder) Decode(data []byte, v interface{}) (interface{}, error) {
+	var tree interface{}
+	if err := yaml.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+
+	return tree, yaml.Unmarshal(data, v)
+}
+
+// Tag returns yaml.
+func (YAMLDecoder) Tag() string {
+	return "yaml"
+}
+
+// JSONDecoder decodes json files. Keys correspond to json tags.
+type JSONDecoder struct{}
+
+// Decode decodes json data.
+func (JSONDecoder) Decode(data []byte, v interface{}) (interface{}, error) {
+	var tree interface{}
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+
+	return tree, json.Unmarshal(data, v)
+}
+
+// Tag returns json.
+func (JSONDecoder) Tag() string {
+	return "json"
+}
+
+// TOMLDecoder decodes toml files. Keys correspond to yaml tags, durations
+// are strings as in yaml, e.g. check_connections_interval = "10m".
+type TOMLDecoder struct{}
+
+// Decode decodes toml data.
+func (TOMLDecoder) Decode(data []byte, v interface{}) (interface{}, error) {
+	var tree map[string]interface{}
+	if err := toml.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+
+	// Struct fields have no toml tags, so the data is decoded by yaml rules.
+	yml, err := yaml.Marshal(tree)
+	if err != nil {
+		return nil, fmt.Errorf("convert toml: %w", err)
+	}
+
+	return YAMLDecoder{}.Decode(yml, v)
+}
+
+// Tag returns yaml.
+func (TOMLDecoder) Tag() string {
+	return "yaml"
+}
+
+// EnvDecoder decodes .env files with flat KEY=value lines. Keys are the same
+// as the environment variables overriding the config but without prefix,
+// e.g. APP_PORT=8080 or CONNECTIONS_POSTGRES_PASSWORD=secret (see ApplyEnv).
+type EnvDecoder struct{}
+
+// Decode decodes env data.
+func (EnvDecoder) Decode(data []byte, v interface{}) (interface{}, error) {
+	vars, err := godotenv.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	environ := make([]string, 0, len(vars))
+	for key, value := range vars {
+		environ = append(environ, key+"="+value)
+	}
+
+	overrides, err := ApplyEnv(v, "", environ)
+
+	// Build the tree of the applied keys, not matched variables are kept as
+	// is to be reported as unknown keys.
+	tree := make(map[string]interface{})
+
+	for _, o := range overrides {
+		setTreeValue(tree, strings.Split(o.Path, "."), envScalar(vars[o.Env]))
+		delete(vars, o.Env)
+	}
+
+	for key, value := range vars {
+		tree[key] = value
+	}
+
+	return tree, err
+}
+
+// Tag returns yaml.
+func (EnvDecoder) Tag() string {
+	return "yaml"
+}
+
+func setTreeValue(tree map[string]interface{}, path []string, value interface{}) {
+	for _, key := range path[:len(path)-1] {
+		child, ok := tree[key].(map[string]interface{})
+		if !ok {
+			child = make(map[string]interface{})
+			tree[key] = child
+		}
+
+		tree = child
+	}
+
+	tree[path[len(path)-1]] = value
+}
+
+// envScalar resolves the value type as yaml scalar, e.g. numbers and
+// booleans.
+func envScalar(value string) interface{} {
+	var v interface{}
+	if err := yaml.Unmarshal([]byte(value), &v); err != nil {
+		return value
+	}
+
+	switch v.(type) {
+	case map[string]interface{}, []interface{}, nil:
+		return value
+	default:
+		return v
+	}
+}
@@ -0,0 +1,109 @@
+package configutil_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/outdead/goservice/internal/utils/configutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookupDecoder(t *testing.T) {
+	for _, ext := range []string{".yaml", ".yml", ".YML", ".json", ".toml", ".env"} {
+		if _, ok := configutil.LookupDecoder(ext); !ok {
+			t.Errorf("decoder for %s expected", ext)
+		}
+	}
+
+	if _, ok := configutil.LookupDecoder(".ini"); ok {
+		t.Error("decoder for .ini is not expected")
+	}
+}
+
+type testDecoder struct{}
+
+func (testDecoder) Decode(data []byte, v interface{}) (interface{}, error) {
+	v.(*TestConfig).App.Port = string(data)
+
+	return map[string]interface{}{"app": map[string]interface{}{"port": string(data)}}, nil
+}
+
+func (testDecoder) Tag() string {
+	return "yaml"
+}
+
+func TestRegisterDecoder(t *testing.T) {
+	configutil.RegisterDecoder(".test", testDecoder{})
+
+	decoder, ok := configutil.LookupDecoder(".test")
+	if !ok {
+		t.Fatal("registered decoder expected")
+	}
+
+	var cfg TestConfig
+	if _, err := decoder.Decode([]byte("8080"), &cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "8080", cfg.App.Port)
+	assert.Contains(t, configutil.Extensions(), ".test")
+}
+
+func TestTOMLDecoder(t *testing.T) {
+	data := `
+[app]
+port = "8080"
+interval = "1m30s"
+hosts = ["a", "b"]
+
+[consumers.test_income]
+routing_key = "rk"
+`
+
+	var cfg TestConfig
+
+	tree, err := configutil.TOMLDecoder{}.Decode([]byte(data), &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "8080", cfg.App.Port)
+	assert.Equal(t, 90*time.Second, cfg.App.Interval)
+	assert.Equal(t, []string{"a", "b"}, cfg.App.Hosts)
+	assert.Equal(t, Consumer{RoutingKey: "rk"}, cfg.Consumers["test_income"])
+	assert.Empty(t, configutil.UnknownKeys(&cfg, tree, "yaml"))
+}
+
+func TestEnvDecoder(t *testing.T) {
+	data := `
+# comment
+APP_PORT=8080
+APP_INTERVAL=10s
+CONSUMERS_TEST_INCOME_ROUTING_KEY="rk"
+APP_PROT=8080
+`
+
+	var cfg TestConfig
+
+	tree, err := configutil.EnvDecoder{}.Decode([]byte(data), &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "8080", cfg.App.Port)
+	assert.Equal(t, 10*time.Second, cfg.App.Interval)
+	assert.Equal(t, Consumer{RoutingKey: "rk"}, cfg.Consumers["test_income"])
+	assert.Equal(t, map[string]interface{}{
+		"app": map[string]interface{}{
+			"port":     8080,
+			"interval": "10s",
+		},
+		"consumers": map[string]interface{}{
+			"test_income": map[string]interface{}{
+				"routing_key": "rk",
+			},
+		},
+		"APP_PROT": "8080",
+	}, tree)
+	assert.Equal(t, []string{"APP_PROT"}, configutil.UnknownKeys(&cfg, tree, "yaml"))
+}
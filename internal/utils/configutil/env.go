@@ -0,0 +1,399 @@
+// Package configutil contains helpers for loading of the config structures.
+package configutil
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrInvalidEnvValue is returned when environment variable value cannot be
+// converted to the config field type.
+var ErrInvalidEnvValue = errors.New("invalid environment variable value")
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Override describes config field which value is taken from the environment
+// variable.
+type Override struct {
+	// Path is the dotted path of the field by yaml keys, e.g.
+	// connections.postgres.password.
+	Path string
+	// Env is the environment variable name, e.g.
+	// GOSERVICE_CONNECTIONS_POSTGRES_PASSWORD.
+	Env string
+}
+
+// EnvName converts name to the environment variable name: letters are
+// upper cased, other symbols except digits are replaced by underscore.
+func EnvName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
+
+// ApplyEnv overrides fields of the struct pointed by v by values from environ
+// in "key=value" form (see os.Environ). Variable names are built from the
+// prefix and yaml keys of the fields, e.g. GOSERVICE_APP_PORT. Map entries are
+// addressed by their keys, e.g. GOSERVICE_CONNECTIONS_RABBITMQ_CONSUMERS_TEST_INCOME_ROUTING_KEY
+// sets routing_key of the test_income consumer and creates the consumer if it
+// does not exist. Durations are parsed by time.ParseDuration, slices are comma
+// separated. Empty prefix means variable names are built from the keys only,
+// e.g. APP_PORT. Returns overridden fields sorted by path.
+func ApplyEnv(v interface{}, prefix string, environ []string) ([]Override, error) {
+	prefix = EnvName(prefix)
+	env := make(map[string]string)
+
+	for _, kv := range environ {
+		if i := strings.IndexByte(kv, '='); i > 0 && hasEnvPrefix(kv[:i], prefix) {
+			env[kv[:i]] = kv[i+1:]
+		}
+	}
+
+	w := envWalker{env: env}
+	if err := w.walk(reflect.ValueOf(v).Elem(), prefix, ""); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(w.overrides, func(i, j int) bool {
+		return w.overrides[i].Path < w.overrides[j].Path
+	})
+
+	return w.overrides, nil
+}
+
+type envWalker struct {
+	env       map[string]string
+	overrides []Override
+}
+
+func (w *envWalker) walk(v reflect.Value, name, path string) error {
+	switch v.Kind() {
+	case reflect.Struct:
+		return w.walkStruct(v, name, path)
+	case reflect.Ptr:
+		if v.IsNil() {
+			if !w.hasPrefix(name) {
+				return nil
+			}
+
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+
+		return w.walk(v.Elem(), name, path)
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+
+		return w.walkMap(v, name, path)
+	case reflect.Interface:
+		// Pointers are walked as is, e.g. configs of the registered drivers.
+		// Interfaces with methods cannot hold parsed scalars.
+		if !v.IsNil() && v.Elem().Kind() == reflect.Ptr {
+			return w.walk(v.Elem(), name, path)
+		}
+
+		if v.NumMethod() != 0 {
+			return nil
+		}
+
+		return w.walkValue(v, name, path)
+	default:
+		return w.walkValue(v, name, path)
+	}
+}
+
+func (w *envWalker) walkValue(v reflect.Value, name, path string) error {
+	value, ok := w.env[name]
+	if !ok {
+		return nil
+	}
+
+	if err := setValue(v, value); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	w.overrides = append(w.overrides, Override{Path: path, Env: name})
+
+	return nil
+}
+
+func (w *envWalker) walkStruct(v reflect.Value, name, path string) error {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue // Unexported.
+		}
+
+		key, inline := Key(field)
+		if key == "-" {
+			continue
+		}
+
+		fieldName, fieldPath := name, path
+		if !inline {
+			fieldName, fieldPath = joinEnv(name, key), JoinPath(path, key)
+		}
+
+		if err := w.walk(v.Field(i), fieldName, fieldPath); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w *envWalker) walkMap(v reflect.Value, name, path string) error {
+	keys := w.mapKeys(v, name)
+	if len(keys) == 0 {
+		return nil
+	}
+
+	if v.IsNil() {
+		v.Set(reflect.MakeMap(v.Type()))
+	}
+
+	for _, key := range keys {
+		// Map values are not addressable, so the value is copied, changed
+		// and put back.
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if cur := v.MapIndex(reflect.ValueOf(key).Convert(v.Type().Key())); cur.IsValid() {
+			elem.Set(cur)
+		}
+
+		if err := w.walk(elem, joinEnv(name, key), JoinPath(path, key)); err != nil {
+			return err
+		}
+
+		if elem.Kind() == reflect.Interface && elem.IsNil() {
+			// Value of unknown type cannot be created by the variables.
+			continue
+		}
+
+		v.SetMapIndex(reflect.ValueOf(key).Convert(v.Type().Key()), elem)
+	}
+
+	return nil
+}
+
+// mapKeys returns keys of the map which are mentioned in the environment.
+// Existing keys are matched first, new keys are lower cased parts of variable
+// names before the map value field names.
+func (w *envWalker) mapKeys(v reflect.Value, name string) []string {
+	keys := make(map[string]bool)
+	known := make(map[string]string) // env name -> key
+
+	iter := v.MapRange()
+	for iter.Next() {
+		key := iter.Key().String()
+		known[joinEnv(name, key)] = key
+	}
+
+	suffixes := leafSuffixes(v.Type().Elem())
+
+	for env := range w.env {
+		if !hasEnvPrefix(env, name) || env == name {
+			continue
+		}
+
+		if key, ok := matchKnown(known, env); ok {
+			keys[key] = true
+
+			continue
+		}
+
+		rest := strings.TrimPrefix(env, name)
+		rest = strings.TrimPrefix(rest, "_")
+
+		for _, suffix := range suffixes {
+			if suffix == "" {
+				keys[strings.ToLower(rest)] = true
+
+				break
+			}
+
+			if strings.HasSuffix(rest, "_"+suffix) && len(rest) > len(suffix)+1 {
+				keys[strings.ToLower(rest[:len(rest)-len(suffix)-1])] = true
+
+				break
+			}
+		}
+	}
+
+	res := make([]string, 0, len(keys))
+	for key := range keys {
+		res = append(res, key)
+	}
+
+	sort.Strings(res)
+
+	return res
+}
+
+func (w *envWalker) hasPrefix(name string) bool {
+	for env := range w.env {
+		if env == name || hasEnvPrefix(env, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// joinEnv joins variable name with the key converted by EnvName.
+func joinEnv(name, key string) string {
+	if name == "" {
+		return EnvName(key)
+	}
+
+	return name + "_" + EnvName(key)
+}
+
+// hasEnvPrefix checks the variable name starts with the prefix followed by
+// underscore. Any name has empty prefix.
+func hasEnvPrefix(env, prefix string) bool {
+	return prefix == "" || strings.HasPrefix(env, prefix+"_")
+}
+
+func matchKnown(known map[string]string, env string) (string, bool) {
+	for prefix, key := range known {
+		if env == prefix || strings.HasPrefix(env, prefix+"_") {
+			return key, true
+		}
+	}
+
+	return "", false
+}
+
+// leafSuffixes returns variable name suffixes of all leaf fields of the type.
+// Empty suffix means the type itself is a leaf. Longer suffixes go first to
+// match the most specific field.
+func leafSuffixes(t reflect.Type) []string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return []string{""}
+	}
+
+	var suffixes []string
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		key, inline := Key(field)
+		if key == "-" {
+			continue
+		}
+
+		for _, suffix := range leafSuffixes(field.Type) {
+			switch {
+			case inline:
+				suffixes = append(suffixes, suffix)
+			case suffix == "":
+				suffixes = append(suffixes, EnvName(key))
+			default:
+				suffixes = append(suffixes, EnvName(key)+"_"+suffix)
+			}
+		}
+	}
+
+	sort.Slice(suffixes, func(i, j int) bool {
+		return len(suffixes[i]) > len(suffixes[j])
+	})
+
+	return suffixes
+}
+
+// setValue converts string value to the type of v and sets it.
+func setValue(v reflect.Value, value string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidEnvValue, err)
+		}
+
+		v.SetInt(int64(d))
+
+		return nil
+	}
+
+	var err error
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		var b bool
+		if b, err = strconv.ParseBool(value); err == nil {
+			v.SetBool(b)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+		if i, err = strconv.ParseInt(value, 10, v.Type().Bits()); err == nil {
+			v.SetInt(i)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var u uint64
+		if u, err = strconv.ParseUint(value, 10, v.Type().Bits()); err == nil {
+			v.SetUint(u)
+		}
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		if f, err = strconv.ParseFloat(value, v.Type().Bits()); err == nil {
+			v.SetFloat(f)
+		}
+	case reflect.Slice:
+		err = setSlice(v, value)
+	case reflect.Interface:
+		// Untyped values, e.g. queue arguments, are parsed as yaml scalars
+		// to get numbers and booleans.
+		var i interface{}
+		if err = yaml.Unmarshal([]byte(value), &i); err == nil {
+			v.Set(reflect.ValueOf(i))
+		}
+	default:
+		return fmt.Errorf("%w: unsupported type %s", ErrInvalidEnvValue, v.Type())
+	}
+
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidEnvValue, err)
+	}
+
+	return nil
+}
+
+func setSlice(v reflect.Value, value string) error {
+	parts := strings.Split(value, ",")
+	slice := reflect.MakeSlice(v.Type(), len(parts), len(parts))
+
+	for i, part := range parts {
+		if err := setValue(slice.Index(i), strings.TrimSpace(part)); err != nil {
+			return err
+		}
+	}
+
+	v.Set(slice)
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package configutil_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/outdead/goservice/internal/utils/configutil"
+	"github.com/stretchr/testify/assert"
+)
+
+type Consumer struct {
+	QueueName  string `yaml:"queue_name"`
+	RoutingKey string `yaml:"routing_key"`
+}
+
+type TestConfig struct {
+	App struct {
+		Port     string        `yaml:"port"`
+		Interval time.Duration `yaml:"interval"`
+		Debug    bool          `yaml:"debug"`
+		Hosts    []string      `yaml:"hosts"`
+	} `yaml:"app"`
+	Consumers map[string]Consumer    `yaml:"consumers"`
+	Args      map[string]interface{} `yaml:"args"`
+	Optional  *Consumer              `yaml:"optional"`
+	Missing   *Consumer              `yaml:"missing"`
+	NoTag     int
+	internal  string
+}
+
+func TestApplyEnv(t *testing.T) {
+	cfg := TestConfig{
+		Consumers: map[string]Consumer{
+			"test_income": {QueueName: "test.income", RoutingKey: "rk.test.income"},
+		},
+	}
+
+	environ := []string{
+		"GOSERVICE_APP_PORT=9090",
+		"GOSERVICE_APP_INTERVAL=1m30s",
+		"GOSERVICE_APP_DEBUG=true",
+		"GOSERVICE_APP_HOSTS=a, b",
+		"GOSERVICE_CONSUMERS_TEST_INCOME_ROUTING_KEY=rk.new",
+		"GOSERVICE_CONSUMERS_NEW_ONE_QUEUE_NAME=new.one",
+		"GOSERVICE_ARGS_X_MAX_PRIORITY=10",
+		"GOSERVICE_OPTIONAL_QUEUE_NAME=optional",
+		"GOSERVICE_NOTAG=5",
+		"OTHER_APP_PORT=1",
+	}
+
+	overrides, err := configutil.ApplyEnv(&cfg, "goservice", environ)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "9090", cfg.App.Port)
+	assert.Equal(t, 90*time.Second, cfg.App.Interval)
+	assert.True(t, cfg.App.Debug)
+	assert.Equal(t, []string{"a", "b"}, cfg.App.Hosts)
+	assert.Equal(t, Consumer{QueueName: "test.income", RoutingKey: "rk.new"}, cfg.Consumers["test_income"])
+	assert.Equal(t, Consumer{QueueName: "new.one"}, cfg.Consumers["new_one"])
+	assert.Equal(t, 10, cfg.Args["x_max_priority"])
+	assert.Equal(t, &Consumer{QueueName: "optional"}, cfg.Optional)
+	assert.Nil(t, cfg.Missing)
+	assert.Equal(t, 5, cfg.NoTag)
+
+	if assert.Len(t, overrides, 9) {
+		assert.Equal(t, configutil.Override{Path: "app.debug", Env: "GOSERVICE_APP_DEBUG"}, overrides[0])
+	}
+}
+
+func TestApplyEnv_InvalidValue(t *testing.T) {
+	var cfg TestConfig
+
+	if _, err := configutil.ApplyEnv(&cfg, "GOSERVICE", []string{"GOSERVICE_APP_INTERVAL=10"}); err == nil {
+		t.Error("invalid duration error expected")
+	}
+}
+
+type Plugin interface {
+	Name() string
+}
+
+func (c *Consumer) Name() string {
+	return c.QueueName
+}
+
+func TestApplyEnv_Interface(t *testing.T) {
+	cfg := struct {
+		Plugins map[string]Plugin `yaml:"plugins"`
+	}{
+		Plugins: map[string]Plugin{"first": &Consumer{QueueName: "first"}},
+	}
+
+	environ := []string{
+		"GOSERVICE_PLUGINS_FIRST_ROUTING_KEY=rk.first",
+		"GOSERVICE_PLUGINS_SECOND_QUEUE_NAME=second",
+	}
+
+	overrides, err := configutil.ApplyEnv(&cfg, "goservice", environ)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, map[string]Plugin{"first": &Consumer{QueueName: "first", RoutingKey: "rk.first"}}, cfg.Plugins)
+	assert.Equal(t, []configutil.Override{{Path: "plugins.first.routing_key", Env: "GOSERVICE_PLUGINS_FIRST_ROUTING_KEY"}}, overrides)
+}
@@ -0,0 +1,133 @@
+package configutil
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Merge deep merges src into dst. Both must be pointers to the same struct
+// type. The tree is the generic representation (map[string]interface{}) of
+// the document src was decoded from; only fields present in the tree are
+// merged, so zero values set explicitly in the document override dst values
+// while absent fields keep them. Structs and maps are merged by keys, other
+// values including lists are replaced. The tag is the struct tag used to
+// decode the document: yaml or json.
+func Merge(dst, src interface{}, tree interface{}, tag string) {
+	merge(reflect.ValueOf(dst).Elem(), reflect.ValueOf(src).Elem(), tree, tag)
+}
+
+func merge(dst, src reflect.Value, tree interface{}, tag string) {
+	node, ok := tree.(map[string]interface{})
+	if !ok {
+		// Scalars, lists and nulls replace the value.
+		dst.Set(src)
+
+		return
+	}
+
+	switch dst.Kind() {
+	case reflect.Struct:
+		mergeStruct(dst, src, node, tag)
+	case reflect.Ptr:
+		if src.IsNil() {
+			dst.Set(src)
+
+			return
+		}
+
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+
+		merge(dst.Elem(), src.Elem(), tree, tag)
+	case reflect.Map:
+		mergeMap(dst, src, node, tag)
+	case reflect.Interface:
+		// Pointers of the same type, e.g. configs of the registered drivers,
+		// are merged, other values are replaced.
+		if dst.IsNil() || src.IsNil() || dst.Elem().Kind() != reflect.Ptr || dst.Elem().Type() != src.Elem().Type() {
+			dst.Set(src)
+
+			return
+		}
+
+		merge(dst.Elem(), src.Elem(), tree, tag)
+	default:
+		dst.Set(src)
+	}
+}
+
+func mergeStruct(dst, src reflect.Value, node map[string]interface{}, tag string) {
+	t := dst.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue // Unexported.
+		}
+
+		key, inline := TagKey(field, tag)
+		if key == "-" {
+			continue
+		}
+
+		if inline {
+			merge(dst.Field(i), src.Field(i), node, tag)
+
+			continue
+		}
+
+		if child, ok := lookup(node, key, tag); ok {
+			merge(dst.Field(i), src.Field(i), child, tag)
+		}
+	}
+}
+
+func mergeMap(dst, src reflect.Value, node map[string]interface{}, tag string) {
+	if src.IsNil() {
+		dst.Set(src)
+
+		return
+	}
+
+	if dst.IsNil() {
+		dst.Set(reflect.MakeMap(dst.Type()))
+	}
+
+	iter := src.MapRange()
+	for iter.Next() {
+		key, value := iter.Key(), iter.Value()
+
+		cur := dst.MapIndex(key)
+		if !cur.IsValid() || key.Kind() != reflect.String {
+			dst.SetMapIndex(key, value)
+
+			continue
+		}
+
+		// Map values are not addressable, so the value is copied, merged
+		// and put back.
+		elem := reflect.New(dst.Type().Elem()).Elem()
+		elem.Set(cur)
+		merge(elem, value, node[key.String()], tag)
+		dst.SetMapIndex(key, elem)
+	}
+}
+
+// lookup returns the node child by the key. Keys are matched case-insensitive
+// for json as encoding/json does.
+func lookup(node map[string]interface{}, key, tag string) (interface{}, bool) {
+	if child, ok := node[key]; ok {
+		return child, true
+	}
+
+	if tag == "json" {
+		for k, child := range node {
+			if strings.EqualFold(k, key) {
+				return child, true
+			}
+		}
+	}
+
+	return nil, false
+}
@@ -0,0 +1,125 @@
+package configutil_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/outdead/goservice/internal/utils/configutil"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func decodeYAML(t *testing.T, data string) (*TestConfig, interface{}) {
+	t.Helper()
+
+	var (
+		cfg  TestConfig
+		tree interface{}
+	)
+
+	if err := yaml.Unmarshal([]byte(data), &cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := yaml.Unmarshal([]byte(data), &tree); err != nil {
+		t.Fatal(err)
+	}
+
+	return &cfg, tree
+}
+
+func TestMerge(t *testing.T) {
+	base := `
+app:
+  port: "8080"
+  interval: 10s
+  debug: true
+  hosts: [a, b]
+consumers:
+  first:
+    queue_name: first
+    routing_key: rk.first
+  second:
+    queue_name: second
+`
+	overlay := `
+app:
+  port: "9090"
+  debug: false
+  hosts: [c]
+consumers:
+  first:
+    routing_key: rk.new
+  third:
+    queue_name: third
+`
+	var cfg TestConfig
+
+	src, tree := decodeYAML(t, base)
+	configutil.Merge(&cfg, src, tree, "yaml")
+
+	src, tree = decodeYAML(t, overlay)
+	configutil.Merge(&cfg, src, tree, "yaml")
+
+	assert.Equal(t, "9090", cfg.App.Port)
+	assert.Equal(t, 10*time.Second, cfg.App.Interval)
+	assert.False(t, cfg.App.Debug)
+	assert.Equal(t, []string{"c"}, cfg.App.Hosts)
+	assert.Equal(t, map[string]Consumer{
+		"first":  {QueueName: "first", RoutingKey: "rk.new"},
+		"second": {QueueName: "second"},
+		"third":  {QueueName: "third"},
+	}, cfg.Consumers)
+}
+
+func TestMerge_JSON(t *testing.T) {
+	cfg := TestConfig{NoTag: 1}
+	cfg.App.Port = "8080"
+
+	data := []byte(`{"NOTAG": 2}`)
+
+	var (
+		src  TestConfig
+		tree interface{}
+	)
+
+	if err := json.Unmarshal(data, &src); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := json.Unmarshal(data, &tree); err != nil {
+		t.Fatal(err)
+	}
+
+	configutil.Merge(&cfg, &src, tree, "json")
+
+	assert.Equal(t, "8080", cfg.App.Port)
+	assert.Equal(t, 2, cfg.NoTag)
+}
+
+func TestMerge_Interface(t *testing.T) {
+	type config struct {
+		Plugins map[string]Plugin `yaml:"plugins"`
+	}
+
+	cfg := config{Plugins: map[string]Plugin{
+		"first":  &Consumer{QueueName: "first", RoutingKey: "rk.first"},
+		"second": &Consumer{QueueName: "second"},
+	}}
+	src := config{Plugins: map[string]Plugin{
+		"first": &Consumer{RoutingKey: "rk.new"},
+	}}
+	tree := map[string]interface{}{
+		"plugins": map[string]interface{}{
+			"first": map[string]interface{}{"routing_key": "rk.new"},
+		},
+	}
+
+	configutil.Merge(&cfg, &src, tree, "yaml")
+
+	assert.Equal(t, map[string]Plugin{
+		"first":  &Consumer{QueueName: "first", RoutingKey: "rk.new"},
+		"second": &Consumer{QueueName: "second"},
+	}, cfg.Plugins)
+}
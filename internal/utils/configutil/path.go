@@ -0,0 +1,56 @@
+package configutil
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Key returns yaml key of the struct field and whether the field is inlined.
+// Fields without yaml tag are keyed by lower cased name as yaml.v3 does.
+func Key(field reflect.StructField) (string, bool) {
+	return TagKey(field, "yaml")
+}
+
+// TagKey returns key of the struct field by the tag (yaml or json) and whether
+// the field is inlined. Fields without the tag are keyed as the corresponding
+// package does: by lower cased name for yaml and by name for json.
+func TagKey(field reflect.StructField, tag string) (string, bool) {
+	value := field.Tag.Get(tag)
+	name := value
+
+	var opts string
+	if i := strings.IndexByte(value, ','); i >= 0 {
+		name, opts = value[:i], value[i+1:]
+	}
+
+	inline := false
+
+	for _, opt := range strings.Split(opts, ",") {
+		if opt == "inline" {
+			inline = true
+		}
+	}
+
+	if name == "" {
+		switch {
+		case tag == "json" && field.Anonymous:
+			// Embedded structs without tag are inlined by encoding/json.
+			inline = true
+		case tag == "json":
+			name = field.Name
+		default:
+			name = strings.ToLower(field.Name)
+		}
+	}
+
+	return name, inline
+}
+
+// JoinPath joins dotted path of the config field with the key.
+func JoinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
@@ -0,0 +1,269 @@
+package configutil
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/outdead/goservice/internal/utils/multierror"
+)
+
+// SchemaDraft is the JSON Schema version of the generated schemas.
+const SchemaDraft = "http://json-schema.org/draft-07/schema#"
+
+// DurationPattern matches durations in time.ParseDuration format, e.g. 1h30m.
+const DurationPattern = `^([-+]?([0-9]+(\.[0-9]*)?|\.[0-9]+)(ns|us|µs|ms|s|m|h))+$|^0$`
+
+// Schema validation errors.
+var (
+	ErrSchemaType    = errors.New("invalid type")
+	ErrSchemaEnum    = errors.New("value is not allowed")
+	ErrSchemaPattern = errors.New("value does not match pattern")
+)
+
+// Enumer is implemented by types with limited set of values, e.g. actions.
+// The values are added to the generated schema as enum.
+type Enumer interface {
+	Enum() []string
+}
+
+// Schema is JSON Schema of the config.
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+}
+
+var enumerType = reflect.TypeOf((*Enumer)(nil)).Elem()
+
+// NewSchema generates JSON Schema of the struct pointed by v. Keys are taken
+// from the tag: yaml or json. Durations are strings in yaml and integers of
+// nanoseconds in json. Fields tagged with `required:"true"` are required,
+// allowed values of the string fields and string list items are listed in
+// `enum:"a,b"` tag.
+func NewSchema(v interface{}, tag string) *Schema {
+	s := newSchema(reflect.TypeOf(v).Elem(), tag)
+	s.Schema = SchemaDraft
+
+	return s
+}
+
+func newSchema(t reflect.Type, tag string) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == durationType {
+		if tag == "json" {
+			return &Schema{Type: "integer", Description: "duration in nanoseconds"}
+		}
+
+		return &Schema{Type: "string", Description: "duration, e.g. 1h30m", Pattern: DurationPattern}
+	}
+
+	s := new(Schema)
+
+	if t.Implements(enumerType) {
+		s.Enum = reflect.Zero(t).Interface().(Enumer).Enum()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		s.Type = "object"
+		s.Properties = make(map[string]*Schema)
+		s.AdditionalProperties = false
+
+		addProperties(s, t, tag)
+	case reflect.Map:
+		s.Type = "object"
+		s.AdditionalProperties = newSchema(t.Elem(), tag)
+	case reflect.Slice, reflect.Array:
+		s.Type = "array"
+		s.Items = newSchema(t.Elem(), tag)
+	case reflect.String:
+		s.Type = "string"
+	case reflect.Bool:
+		s.Type = "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s.Type = "integer"
+	case reflect.Float32, reflect.Float64:
+		s.Type = "number"
+	}
+
+	return s
+}
+
+func addProperties(s *Schema, t reflect.Type, tag string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue // Unexported.
+		}
+
+		key, inline := TagKey(field, tag)
+		if key == "-" {
+			continue
+		}
+
+		if inline {
+			ft := field.Type
+			for ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+
+			if ft.Kind() == reflect.Struct {
+				addProperties(s, ft, tag)
+			}
+
+			continue
+		}
+
+		prop := newSchema(field.Type, tag)
+
+		if enum := field.Tag.Get("enum"); enum != "" {
+			// Enum of the list restricts its items.
+			if prop.Items != nil {
+				prop.Items.Enum = strings.Split(enum, ",")
+			} else {
+				prop.Enum = strings.Split(enum, ",")
+			}
+		}
+
+		if field.Tag.Get("required") == "true" {
+			s.Required = append(s.Required, key)
+		}
+
+		s.Properties[key] = prop
+	}
+
+	sort.Strings(s.Required)
+}
+
+// Validate checks types, enums and patterns of the tree (generic
+// representation of the decoded document) values. Required fields and
+// unknown keys are not checked: documents can be partial overlays merged
+// with other ones, so these checks are left to the merged config validation.
+// Scalars of any type are accepted as strings as yaml decodes them so.
+func (s *Schema) Validate(tree interface{}) error {
+	errs := multierror.New()
+
+	s.validate(tree, "", errs)
+
+	if errs.Len() != 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (s *Schema) validate(value interface{}, path string, errs multierror.Error) {
+	if value == nil {
+		// Nulls are decoded to zero values.
+		return
+	}
+
+	if !s.matchType(value) {
+		errs.Append(multierror.Field(path, fmt.Errorf("%w: expected %s", ErrSchemaType, s.Type)))
+
+		return
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			if prop, ok := s.property(key); ok {
+				prop.validate(v[key], JoinPath(path, key), errs)
+			}
+		}
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(item, fmt.Sprintf("%s[%d]", path, i), errs)
+			}
+		}
+	default:
+		s.validateScalar(value, path, errs)
+	}
+}
+
+func (s *Schema) validateScalar(value interface{}, path string, errs multierror.Error) {
+	str := fmt.Sprint(value)
+
+	if len(s.Enum) != 0 {
+		found := false
+
+		for _, e := range s.Enum {
+			if e == str {
+				found = true
+
+				break
+			}
+		}
+
+		if !found {
+			errs.Append(multierror.Field(path, fmt.Errorf("%w: %q, allowed: %s", ErrSchemaEnum, str, strings.Join(s.Enum, ", "))))
+		}
+	}
+
+	if s.Pattern != "" {
+		if _, ok := value.(string); ok && !regexp.MustCompile(s.Pattern).MatchString(str) {
+			errs.Append(multierror.Field(path, fmt.Errorf("%w: %q", ErrSchemaPattern, str)))
+		}
+	}
+}
+
+// property returns schema of the object property or additional properties.
+func (s *Schema) property(key string) (*Schema, bool) {
+	if prop, ok := s.Properties[key]; ok {
+		return prop, true
+	}
+
+	if prop, ok := s.AdditionalProperties.(*Schema); ok {
+		return prop, true
+	}
+
+	return nil, false
+}
+
+func (s *Schema) matchType(value interface{}) bool {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return s.Type == "object" || s.Type == ""
+	case []interface{}:
+		return s.Type == "array" || s.Type == ""
+	case bool:
+		return s.Type == "boolean" || s.Type == "string" || s.Type == ""
+	case string:
+		return s.Type == "string" || s.Type == ""
+	case int, int64, uint64:
+		return s.Type == "integer" || s.Type == "number" || s.Type == "string" || s.Type == ""
+	case float64:
+		if s.Type == "integer" {
+			// JSON numbers are decoded to float64.
+			return v == math.Trunc(v)
+		}
+
+		return s.Type == "number" || s.Type == "string" || s.Type == ""
+	default:
+		return true
+	}
+}
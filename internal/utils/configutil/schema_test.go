@@ -0,0 +1,94 @@
+package configutil_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/outdead/goservice/internal/utils/configutil"
+	"github.com/outdead/goservice/internal/utils/multierror"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+type Level string
+
+func (Level) Enum() []string {
+	return []string{"info", "debug"}
+}
+
+type SchemaConfig struct {
+	Addr     string            `yaml:"addr" json:"addr" required:"true"`
+	Type     string            `yaml:"type" json:"type" enum:"direct,fanout"`
+	Level    Level             `yaml:"level" json:"level"`
+	Interval time.Duration     `yaml:"interval" json:"interval"`
+	Size     int               `yaml:"size" json:"size"`
+	Hosts    []string          `yaml:"hosts" json:"hosts"`
+	Backends []string          `yaml:"backends" json:"backends" enum:"postgres,redis"`
+	Byname   map[string]Secret `yaml:"byname" json:"by_name"`
+}
+
+func TestNewSchema(t *testing.T) {
+	s := configutil.NewSchema(&SchemaConfig{}, "yaml")
+
+	assert.Equal(t, configutil.SchemaDraft, s.Schema)
+	assert.Equal(t, "object", s.Type)
+	assert.Equal(t, false, s.AdditionalProperties)
+	assert.Equal(t, []string{"addr"}, s.Required)
+	assert.Equal(t, []string{"direct", "fanout"}, s.Properties["type"].Enum)
+	assert.Equal(t, []string{"info", "debug"}, s.Properties["level"].Enum)
+	assert.Equal(t, "string", s.Properties["interval"].Type)
+	assert.Equal(t, configutil.DurationPattern, s.Properties["interval"].Pattern)
+	assert.Equal(t, "integer", s.Properties["size"].Type)
+	assert.Equal(t, "string", s.Properties["hosts"].Items.Type)
+	assert.Equal(t, []string{"postgres", "redis"}, s.Properties["backends"].Items.Enum)
+	assert.Equal(t, "object", s.Properties["byname"].AdditionalProperties.(*configutil.Schema).Type)
+
+	s = configutil.NewSchema(&SchemaConfig{}, "json")
+
+	assert.Equal(t, "integer", s.Properties["interval"].Type)
+	assert.Contains(t, s.Properties, "by_name")
+}
+
+func TestSchema_Validate(t *testing.T) {
+	data := `
+type: topic
+level: trace
+interval: 10 minutes
+size: many
+hosts: [a, 1]
+byname:
+  first:
+    token: 1
+unknown: key
+`
+
+	var tree interface{}
+	if err := yaml.Unmarshal([]byte(data), &tree); err != nil {
+		t.Fatal(err)
+	}
+
+	err := configutil.NewSchema(&SchemaConfig{}, "yaml").Validate(tree)
+	if err == nil {
+		t.Fatal("schema validation error expected")
+	}
+
+	var merr multierror.Error
+	if !errors.As(err, &merr) {
+		t.Fatalf("multierror expected, got %T", err)
+	}
+
+	if assert.Len(t, merr.Errors(), 4) {
+		assert.True(t, errors.Is(merr.Errors()[0], configutil.ErrSchemaPattern))
+		assert.True(t, errors.Is(merr.Errors()[1], configutil.ErrSchemaEnum))
+		assert.True(t, errors.Is(merr.Errors()[2], configutil.ErrSchemaType))
+		assert.True(t, errors.Is(merr.Errors()[3], configutil.ErrSchemaEnum))
+		assert.Equal(t, "size: invalid type: expected integer", merr.Errors()[2].Error())
+	}
+
+	assert.NoError(t, configutil.NewSchema(&SchemaConfig{}, "yaml").Validate(map[string]interface{}{
+		"addr":     "localhost",
+		"interval": "1h30m",
+		"size":     10,
+	}))
+}
@@ -0,0 +1,153 @@
+package configutil
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// UnknownKeys returns dotted paths of the keys of the tree (generic
+// representation of the decoded document) which do not match any field of
+// the struct pointed by v. The tag is the struct tag used to decode the
+// document: yaml or json. Interface values are checked by their dynamic
+// types, e.g. configs of the registered drivers, so v must be decoded from
+// the document. Nil interfaces and empty interfaces accept any keys.
+func UnknownKeys(v interface{}, tree interface{}, tag string) []string {
+	var keys []string
+
+	unknownKeys(reflect.ValueOf(v), tree, tag, "", &keys)
+	sort.Strings(keys)
+
+	return keys
+}
+
+func unknownKeys(v reflect.Value, tree interface{}, tag, path string, keys *[]string) {
+	node, ok := tree.(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	v = indirect(v)
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if !v.IsNil() {
+			unknownKeys(v.Elem(), tree, tag, path, keys)
+		}
+	case reflect.Struct:
+		fields := structFields(v.Type(), tag)
+
+		for key, child := range node {
+			field, ok := findField(fields, key, tag)
+			if !ok {
+				*keys = append(*keys, JoinPath(path, key))
+
+				continue
+			}
+
+			unknownKeys(fieldByIndex(v, field.Index), child, tag, JoinPath(path, key), keys)
+		}
+	case reflect.Map:
+		for key, child := range node {
+			// Absent keys are checked by the zero value of the map type.
+			elem := reflect.Value{}
+			if v.Type().Key().Kind() == reflect.String {
+				elem = v.MapIndex(reflect.ValueOf(key).Convert(v.Type().Key()))
+			}
+
+			if !elem.IsValid() {
+				elem = reflect.Zero(v.Type().Elem())
+			}
+
+			unknownKeys(elem, child, tag, JoinPath(path, key), keys)
+		}
+	}
+}
+
+// indirect dereferences pointers. Nil pointers are replaced by zero values
+// of their types.
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v = reflect.Zero(v.Type().Elem())
+
+			continue
+		}
+
+		v = v.Elem()
+	}
+
+	return v
+}
+
+// fieldByIndex returns the struct field by index as FieldByIndex does but
+// does not panic on nil pointers of the inlined structs.
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 {
+			v = indirect(v)
+		}
+
+		v = v.Field(x)
+	}
+
+	return v
+}
+
+// structFields returns fields of the struct including fields of the inlined
+// structs by keys. Index of the inlined fields is relative to the struct.
+func structFields(t reflect.Type, tag string) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue // Unexported.
+		}
+
+		key, inline := TagKey(field, tag)
+		if key == "-" {
+			continue
+		}
+
+		if inline {
+			ft := field.Type
+			for ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+
+			if ft.Kind() == reflect.Struct {
+				for k, f := range structFields(ft, tag) {
+					f.Index = append([]int{i}, f.Index...)
+					fields[k] = f
+				}
+			}
+
+			continue
+		}
+
+		if field.PkgPath == "" {
+			fields[key] = field
+		}
+	}
+
+	return fields
+}
+
+// findField returns the field by key. Keys are matched case-insensitive for
+// json as encoding/json does.
+func findField(fields map[string]reflect.StructField, key, tag string) (reflect.StructField, bool) {
+	if field, ok := fields[key]; ok {
+		return field, true
+	}
+
+	if tag == "json" {
+		for k, field := range fields {
+			if strings.EqualFold(k, key) {
+				return field, true
+			}
+		}
+	}
+
+	return reflect.StructField{}, false
+}
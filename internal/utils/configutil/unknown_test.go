@@ -0,0 +1,81 @@
+package configutil_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/outdead/goservice/internal/utils/configutil"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestUnknownKeys(t *testing.T) {
+	data := `
+app:
+  port: "8080"
+  prot: "8080"
+consumers:
+  first:
+    queue_name: first
+    routing: rk
+args:
+  any: key
+optional:
+  name: value
+unknown: true
+`
+
+	var tree interface{}
+	if err := yaml.Unmarshal([]byte(data), &tree); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []string{
+		"app.prot",
+		"consumers.first.routing",
+		"optional.name",
+		"unknown",
+	}, configutil.UnknownKeys(&TestConfig{}, tree, "yaml"))
+}
+
+func TestUnknownKeys_JSON(t *testing.T) {
+	var tree interface{}
+	if err := json.Unmarshal([]byte(`{"App": {"Port": "8080", "Prot": "8080"}, "notag": 1, "internal": "x"}`), &tree); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []string{"App.Prot", "internal"}, configutil.UnknownKeys(&TestConfig{}, tree, "json"))
+}
+
+// Section is implemented by configs decoded to interface values.
+type Section interface {
+	IsEnabled() bool
+}
+
+func (c *Consumer) IsEnabled() bool { return c != nil }
+
+func TestUnknownKeys_Interface(t *testing.T) {
+	data := `
+sections:
+  first:
+    queue_name: first
+    routing: rk
+  unknown:
+    any: key
+`
+
+	var tree interface{}
+	if err := yaml.Unmarshal([]byte(data), &tree); err != nil {
+		t.Fatal(err)
+	}
+
+	// Dynamic type of the interface value is checked, nil interface
+	// accepts any keys.
+	cfg := struct {
+		Sections map[string]Section `yaml:"sections"`
+	}{
+		Sections: map[string]Section{"first": &Consumer{}, "unknown": nil},
+	}
+
+	assert.Equal(t, []string{"sections.first.routing"}, configutil.UnknownKeys(&cfg, tree, "yaml"))
+}
@@ -3,6 +3,9 @@ package clickhouse
 import (
 	"errors"
 	"fmt"
+
+	"github.com/outdead/goservice/internal/utils/backoff"
+	"github.com/outdead/goservice/internal/utils/multierror"
 )
 
 // Config validation errors.
@@ -12,16 +15,41 @@ var (
 
 // Config contains credentials for ClickHouse database.
 type Config struct {
-	Addr     string `yaml:"addr" json:"addr"`
-	Database string `yaml:"database" json:"database"`
-	Debug    bool   `yaml:"debug" json:"debug"`
-	ZoneInfo string `yaml:"zoneinfo" json:"zone_info"`
+	Disabled bool                `yaml:"disabled" json:"disabled"`
+	Addr     string              `yaml:"addr" json:"addr"`
+	Database string              `yaml:"database" json:"database"`
+	Debug    bool                `yaml:"debug" json:"debug"`
+	ZoneInfo string              `yaml:"zoneinfo" json:"zone_info"`
+	Startup  backoff.RetryConfig `yaml:"startup" json:"startup"`
+}
+
+// SetDefaults sets default values of the fields which are not set.
+func (cfg *Config) SetDefaults() {
+	cfg.Startup.SetDefaults()
+}
+
+// IsEnabled reports whether the connection is configured and not disabled.
+func (cfg *Config) IsEnabled() bool {
+	return cfg != nil && !cfg.Disabled
 }
 
 // Validate checks required fields and validates for allowed values.
 func (cfg Config) Validate() error {
+	if cfg.Disabled {
+		// Do not validate disabled connection.
+		return nil
+	}
+
+	errs := multierror.New()
+
 	if cfg.Addr == "" {
-		return ErrEmptyAddr
+		errs.Append(multierror.Field("addr", ErrEmptyAddr))
+	}
+
+	errs.Append(multierror.Prefix(cfg.Startup.Validate(), "startup"))
+
+	if errs.Len() != 0 {
+		return errs
 	}
 
 	return nil
@@ -3,6 +3,8 @@ package clickhouse
 import (
 	"fmt"
 	"testing"
+
+	"github.com/outdead/goservice/internal/utils/backoff"
 )
 
 var config = Config{
@@ -20,6 +22,8 @@ func TestConfig_Validate(t *testing.T) {
 	}{
 		{"positive validation", config, false},
 		{"empty addr", Config{}, true},
+		{"disabled", Config{Disabled: true}, false},
+		{"negative startup max_wait", Config{Addr: "localhost:9000", Startup: backoff.RetryConfig{MaxWait: -1}}, true},
 	}
 
 	for _, tt := range tests {
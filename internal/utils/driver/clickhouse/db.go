@@ -1,6 +1,7 @@
 package clickhouse
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -19,6 +20,15 @@ const DefaultBatchLimit = 5000
 // ErrLostConnection is returned when connection to database was lost.
 var ErrLostConnection = errors.New("clickhouse: connection is lost")
 
+// Database is the interface of the ClickHouse database used by the service.
+// It is implemented by DB and by the in-memory fake for unit tests.
+type Database interface {
+	GetServerTime() (time.Time, error)
+	GetServerTimeContext(ctx context.Context) (time.Time, error)
+	MultiInsert(query string, rows [][]interface{}) error
+	MultiInsertContext(ctx context.Context, query string, rows [][]interface{}) error
+}
+
 // DB is a wrapper around sqlx.DB which keeps track of the ClickHouse database.
 type DB struct {
 	config *Config
@@ -57,13 +67,19 @@ func (db *DB) DB() *sqlx.DB {
 	return db.db
 }
 
-// IsConnected() checks connection status to database.
+// IsConnected checks connection status to database.
 func (db *DB) IsConnected() bool {
+	return db.IsConnectedContext(context.Background())
+}
+
+// IsConnectedContext checks connection status to database. The check is
+// aborted when ctx is done.
+func (db *DB) IsConnectedContext(ctx context.Context) bool {
 	if db.db == nil {
 		return false
 	}
 
-	if err := db.db.Ping(); err != nil {
+	if err := db.db.PingContext(ctx); err != nil {
 		return false
 	}
 
@@ -72,13 +88,19 @@ func (db *DB) IsConnected() bool {
 
 // GetServerTime returns database server time or error.
 func (db *DB) GetServerTime() (time.Time, error) {
+	return db.GetServerTimeContext(context.Background())
+}
+
+// GetServerTimeContext returns database server time or error. The query is
+// aborted when ctx is done.
+func (db *DB) GetServerTimeContext(ctx context.Context) (time.Time, error) {
 	var st time.Time
 
 	if db.db == nil {
 		return st, ErrLostConnection
 	}
 
-	if err := db.db.QueryRow("SELECT now()").Scan(&st); err != nil {
+	if err := db.db.QueryRowContext(ctx, "SELECT now()").Scan(&st); err != nil {
 		return st, fmt.Errorf("clickhouse: %w", err)
 	}
 
@@ -87,16 +109,22 @@ func (db *DB) GetServerTime() (time.Time, error) {
 
 // MultiInsert performs a transactional insert of multiple records.
 func (db *DB) MultiInsert(query string, rows [][]interface{}) error {
+	return db.MultiInsertContext(context.Background(), query, rows)
+}
+
+// MultiInsertContext performs a transactional insert of multiple records. The
+// transaction is rolled back when ctx is done before commit.
+func (db *DB) MultiInsertContext(ctx context.Context, query string, rows [][]interface{}) error {
 	if db.db == nil {
 		return ErrLostConnection
 	}
 
-	tx, err := db.db.Begin()
+	tx, err := db.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("clickhouse: %w", err)
 	}
 
-	stmt, err := tx.Prepare(query)
+	stmt, err := tx.PrepareContext(ctx, query)
 	if err != nil {
 		if err2 := tx.Rollback(); err2 != nil {
 			return fmt.Errorf("clickhouse multiple errors: %w", multierror.New(err, err2))
@@ -108,7 +136,7 @@ func (db *DB) MultiInsert(query string, rows [][]interface{}) error {
 	defer stmt.Close()
 
 	for i := range rows {
-		if _, err := stmt.Exec(rows[i]...); err != nil {
+		if _, err := stmt.ExecContext(ctx, rows[i]...); err != nil {
 			if err2 := tx.Rollback(); err2 != nil {
 				return fmt.Errorf("clickhouse multiple errors: %w", multierror.New(err, err2))
 			}
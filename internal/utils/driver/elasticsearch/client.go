@@ -15,24 +15,35 @@ const DefaultBatchLimit = 5000
 // ErrLostConnection is returned when connection to database was lost.
 var ErrLostConnection = errors.New("elasticsearch: connection is lost")
 
+// Database is the interface of the Elasticsearch database used by the
+// service. It is implemented by Client and by the in-memory fake for unit
+// tests.
+type Database interface {
+	MultiInsert(rows []Model) error
+	MultiInsertContext(ctx context.Context, rows []Model) error
+	IsErrNotFound(err error) bool
+}
+
 // Client is a wrapper around elastic.Client which keeps track of the Elasticsearch
 // database.
 type Client struct {
 	config *Config
 	conn   *elastic.Client
-	ctx    context.Context
 }
 
 // NewDB creates new connection to Elasticsearch using olivere/elastic.
 func NewClient(cfg *Config) (*Client, error) {
-	if cfg.HealthcheckInterval == 0 {
-		cfg.HealthcheckInterval = DefaultHealthcheckInterval
+	// Config is not changed here, defaults are expected to be set by
+	// Config.SetDefaults. Zero interval disables healthcheck in elastic.
+	interval := cfg.HealthcheckInterval
+	if interval == 0 {
+		interval = DefaultHealthcheckInterval
 	}
 
 	conn, err := elastic.NewClient(
 		elastic.SetSniff(true),
 		elastic.SetURL(cfg.Addr),
-		elastic.SetHealthcheckInterval(cfg.HealthcheckInterval),
+		elastic.SetHealthcheckInterval(interval),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("connect: %w", err)
@@ -41,7 +52,6 @@ func NewClient(cfg *Config) (*Client, error) {
 	client := Client{
 		config: cfg,
 		conn:   conn,
-		ctx:    context.Background(),
 	}
 
 	return &client, nil
@@ -57,8 +67,33 @@ func (client *Client) Conn() *elastic.Client {
 	return client.conn
 }
 
+// IsConnected checks connection status to database.
+func (client *Client) IsConnected() bool {
+	return client.IsConnectedContext(context.Background())
+}
+
+// IsConnectedContext checks connection status to database. The check is
+// aborted when ctx is done.
+func (client *Client) IsConnectedContext(ctx context.Context) bool {
+	if client == nil || client.conn == nil {
+		return false
+	}
+
+	if _, _, err := client.conn.Ping(client.config.Addr).Do(ctx); err != nil {
+		return false
+	}
+
+	return true
+}
+
 // MultiInsert performs a bulk insert of multiple records.
 func (client *Client) MultiInsert(rows []Model) error {
+	return client.MultiInsertContext(context.Background(), rows)
+}
+
+// MultiInsertContext performs a bulk insert of multiple records. The request
+// is aborted when ctx is done.
+func (client *Client) MultiInsertContext(ctx context.Context, rows []Model) error {
 	if client.conn == nil {
 		return ErrLostConnection
 	}
@@ -75,7 +110,7 @@ func (client *Client) MultiInsert(rows []Model) error {
 		bulk = bulk.Add(req)
 	}
 
-	if _, err := bulk.Do(client.ctx); err != nil {
+	if _, err := bulk.Do(ctx); err != nil {
 		return fmt.Errorf("elasticsearch: %w", err)
 	}
 
@@ -3,8 +3,13 @@ package elasticsearch
 import (
 	"errors"
 	"time"
+
+	"github.com/outdead/goservice/internal/utils/backoff"
+	"github.com/outdead/goservice/internal/utils/multierror"
 )
 
+// DefaultHealthcheckInterval is the interval of the nodes healthcheck if
+// healthcheck_interval is not set.
 const DefaultHealthcheckInterval = 5 * time.Second
 
 // Config validation errors.
@@ -16,23 +21,52 @@ var (
 
 // Config contains credentials for Elasticsearch database.
 type Config struct {
-	Addr                string        `yaml:"addr" json:"addr"`
-	Database            string        `yaml:"database" json:"database"`
-	HealthcheckInterval time.Duration `json:"healthcheck_interval"`
+	Disabled            bool                `yaml:"disabled" json:"disabled"`
+	Addr                string              `yaml:"addr" json:"addr" secret:"url"`
+	Database            string              `yaml:"database" json:"database"`
+	HealthcheckInterval time.Duration       `yaml:"healthcheck_interval" json:"healthcheck_interval"`
+	Startup             backoff.RetryConfig `yaml:"startup" json:"startup"`
+}
+
+// SetDefaults sets default values of the fields which are not set.
+func (cfg *Config) SetDefaults() {
+	if cfg.HealthcheckInterval == 0 {
+		cfg.HealthcheckInterval = DefaultHealthcheckInterval
+	}
+
+	cfg.Startup.SetDefaults()
+}
+
+// IsEnabled reports whether the connection is configured and not disabled.
+func (cfg *Config) IsEnabled() bool {
+	return cfg != nil && !cfg.Disabled
 }
 
 // Validate checks required fields and validates for allowed values.
 func (cfg Config) Validate() error {
+	if cfg.Disabled {
+		// Do not validate disabled connection.
+		return nil
+	}
+
+	errs := multierror.New()
+
 	if cfg.Addr == "" {
-		return ErrEmptyAddr
+		errs.Append(multierror.Field("addr", ErrEmptyAddr))
 	}
 
 	if cfg.Database == "" {
-		return ErrEmptyDatabase
+		errs.Append(multierror.Field("database", ErrEmptyDatabase))
 	}
 
 	if cfg.HealthcheckInterval < 0 {
-		return ErrHealthcheckInterval
+		errs.Append(multierror.Field("healthcheck_interval", ErrHealthcheckInterval))
+	}
+
+	errs.Append(multierror.Prefix(cfg.Startup.Validate(), "startup"))
+
+	if errs.Len() != 0 {
+		return errs
 	}
 
 	return nil
@@ -2,6 +2,7 @@ package elasticsearch_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/outdead/goservice/internal/utils/driver/elasticsearch"
 )
@@ -20,6 +21,7 @@ func TestConfig_Validate(t *testing.T) {
 	}{
 		{"positive validation", config, false},
 		{"empty addr", elasticsearch.Config{}, true},
+		{"disabled", elasticsearch.Config{Disabled: true}, false},
 		{"empty database", elasticsearch.Config{Addr: "http://localhost:9200"}, true},
 		{"negative healthcheck_interval", elasticsearch.Config{Addr: "http://localhost:9200", HealthcheckInterval: -1}, true},
 	}
@@ -32,3 +34,19 @@ func TestConfig_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestConfig_SetDefaults(t *testing.T) {
+	cfg := elasticsearch.Config{}
+	cfg.SetDefaults()
+
+	if cfg.HealthcheckInterval != elasticsearch.DefaultHealthcheckInterval {
+		t.Errorf("healthcheck_interval expected: %s, got %s", elasticsearch.DefaultHealthcheckInterval, cfg.HealthcheckInterval)
+	}
+
+	cfg = elasticsearch.Config{HealthcheckInterval: time.Minute}
+	cfg.SetDefaults()
+
+	if cfg.HealthcheckInterval != time.Minute {
+		t.Errorf("healthcheck_interval expected: %s, got %s", time.Minute, cfg.HealthcheckInterval)
+	}
+}
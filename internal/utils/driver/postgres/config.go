@@ -3,45 +3,89 @@ package postgres
 import (
 	"errors"
 	"fmt"
+	"time"
+
+	"github.com/outdead/goservice/internal/utils/backoff"
+	"github.com/outdead/goservice/internal/utils/multierror"
 )
 
+// DefaultPoolTimeout is the time the client waits for a free connection if
+// all connections are busy and pool_timeout is not set.
+const DefaultPoolTimeout = time.Hour
+
 // Config validation errors.
 var (
 	ErrEmptyAddr     = errors.New("addr is empty")
 	ErrEmptyDatabase = errors.New("database is empty")
 	ErrEmptyUser     = errors.New("user is empty")
 	ErrEmptyPassword = errors.New("password is empty")
+
+	ErrInvalidPoolTimeout = errors.New("pool_timeout must be positive number or zero")
 )
 
 // Config contains credentials for PostgreSQL database.
 type Config struct {
-	Addr         string            `yaml:"addr" json:"addr"`
-	Database     string            `yaml:"database" json:"database"`
-	User         string            `yaml:"username" json:"user"`
-	Password     string            `yaml:"password" json:"password"`
-	Notify       map[string]string `yaml:"notify" json:"notify"`
-	Debug        bool              `yaml:"debug" json:"debug"`
-	PoolSize     int               `yaml:"pool_size" json:"pool_size"`
-	MaxIdleConns int               `yaml:"max_idle_conns" json:"max_idle_conns"`
-	MaxOpenConns int               `yaml:"max_open_conns" json:"max_open_conns"`
+	Disabled     bool                `yaml:"disabled" json:"disabled"`
+	Addr         string              `yaml:"addr" json:"addr"`
+	Database     string              `yaml:"database" json:"database"`
+	User         string              `yaml:"username" json:"user"`
+	Password     string              `yaml:"password" json:"password" secret:"true"`
+	Notify       map[string]string   `yaml:"notify" json:"notify"`
+	Debug        bool                `yaml:"debug" json:"debug"`
+	PoolSize     int                 `yaml:"pool_size" json:"pool_size"`
+	PoolTimeout  time.Duration       `yaml:"pool_timeout" json:"pool_timeout"`
+	MaxIdleConns int                 `yaml:"max_idle_conns" json:"max_idle_conns"`
+	MaxOpenConns int                 `yaml:"max_open_conns" json:"max_open_conns"`
+	Startup      backoff.RetryConfig `yaml:"startup" json:"startup"`
+}
+
+// SetDefaults sets default values of the fields which are not set.
+func (cfg *Config) SetDefaults() {
+	if cfg.PoolTimeout == 0 {
+		cfg.PoolTimeout = DefaultPoolTimeout
+	}
+
+	cfg.Startup.SetDefaults()
+}
+
+// IsEnabled reports whether the connection is configured and not disabled.
+func (cfg *Config) IsEnabled() bool {
+	return cfg != nil && !cfg.Disabled
 }
 
 // Validate checks required fields and validates for allowed values.
 func (cfg *Config) Validate() error {
+	if cfg.Disabled {
+		// Do not validate disabled connection.
+		return nil
+	}
+
+	errs := multierror.New()
+
 	if cfg.Addr == "" {
-		return ErrEmptyAddr
+		errs.Append(multierror.Field("addr", ErrEmptyAddr))
 	}
 
 	if cfg.Database == "" {
-		return ErrEmptyDatabase
+		errs.Append(multierror.Field("database", ErrEmptyDatabase))
 	}
 
 	if cfg.User == "" {
-		return ErrEmptyUser
+		errs.Append(multierror.Field("username", ErrEmptyUser))
 	}
 
 	if cfg.Password == "" {
-		return ErrEmptyPassword
+		errs.Append(multierror.Field("password", ErrEmptyPassword))
+	}
+
+	if cfg.PoolTimeout < 0 {
+		errs.Append(multierror.Field("pool_timeout", ErrInvalidPoolTimeout))
+	}
+
+	errs.Append(multierror.Prefix(cfg.Startup.Validate(), "startup"))
+
+	if errs.Len() != 0 {
+		return errs
 	}
 
 	return nil
@@ -19,6 +19,7 @@ func TestConfig_Validate(t *testing.T) {
 			Debug:    true,
 		}, false},
 		{"empty addr", Config{}, true},
+		{"disabled", Config{Disabled: true}, false},
 		{"empty database", Config{Addr: "localhost:5432"}, true},
 		{"empty user", Config{Addr: "localhost:5432", Database: "goservice"}, true},
 		{"empty password", Config{Addr: "localhost:5432", Database: "goservice", User: "postgres"}, true},
@@ -49,3 +50,26 @@ func TestConfig_GetDataSourceNameN(t *testing.T) {
 		t.Errorf("dns expected: %q, got %q", expected, got)
 	}
 }
+
+func TestConfig_Validate_AllErrors(t *testing.T) {
+	cfg := Config{}
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("validation error expected")
+	}
+
+	want := "addr is empty, database is empty, username: user is empty, password is empty"
+	if err.Error() != want {
+		t.Errorf("error expected: %q, got %q", want, err.Error())
+	}
+}
+
+func TestConfig_SetDefaults(t *testing.T) {
+	cfg := Config{}
+	cfg.SetDefaults()
+
+	if cfg.PoolTimeout != DefaultPoolTimeout {
+		t.Errorf("pool_timeout expected: %s, got %s", DefaultPoolTimeout, cfg.PoolTimeout)
+	}
+}
@@ -1,6 +1,7 @@
 package postgres
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -12,13 +13,28 @@ import (
 // ErrLostConnection is returned when connection to database was lost.
 var ErrLostConnection = errors.New("postgres: connection is lost")
 
+// Database is the interface of the PostgreSQL database used by the service.
+// It is implemented by DB and by the in-memory fake for unit tests.
+type Database interface {
+	Exec(query interface{}, params ...interface{}) (pg.Result, error)
+	ExecContext(ctx context.Context, query interface{}, params ...interface{}) (pg.Result, error)
+	Query(model, query interface{}, params ...interface{}) (pg.Result, error)
+	QueryContext(ctx context.Context, model, query interface{}, params ...interface{}) (pg.Result, error)
+	QueryOne(model, query interface{}, params ...interface{}) (pg.Result, error)
+	QueryOneContext(ctx context.Context, model, query interface{}, params ...interface{}) (pg.Result, error)
+	GetServerTime() (time.Time, error)
+	GetServerTimeContext(ctx context.Context) (time.Time, error)
+	IsErrNoRows(err error) bool
+}
+
 // DB is a wrapper around pg.DB which keeps track of the PostgreSQL database.
 type DB struct {
 	config *Config
 	db     *pg.DB
 }
 
-// NewDB creates new connection to PostgreSQL using pg.v9.
+// NewDB creates new connection to PostgreSQL using pg.v9. Defaults of the
+// config must be set (see Config.SetDefaults).
 func NewDB(cfg *Config) (*DB, error) {
 	db := DB{config: cfg, db: pg.Connect(&pg.Options{
 		Addr:        cfg.Addr,
@@ -26,7 +42,7 @@ func NewDB(cfg *Config) (*DB, error) {
 		Password:    cfg.Password,
 		Database:    cfg.Database,
 		PoolSize:    cfg.PoolSize,
-		PoolTimeout: time.Hour,
+		PoolTimeout: cfg.PoolTimeout,
 	})}
 
 	if cfg.Debug {
@@ -54,11 +70,17 @@ func (db *DB) DB() *pg.DB {
 
 // IsConnected checks connection status to database.
 func (db *DB) IsConnected() bool {
+	return db.IsConnectedContext(context.Background())
+}
+
+// IsConnectedContext checks connection status to database. The check is
+// aborted when ctx is done.
+func (db *DB) IsConnectedContext(ctx context.Context) bool {
 	if db == nil {
 		return false
 	}
 
-	if _, err := db.GetServerTime(); err != nil {
+	if _, err := db.GetServerTimeContext(ctx); err != nil {
 		return false
 	}
 
@@ -67,19 +89,86 @@ func (db *DB) IsConnected() bool {
 
 // GetServerTime returns database server time or error.
 func (db *DB) GetServerTime() (time.Time, error) {
+	return db.GetServerTimeContext(context.Background())
+}
+
+// GetServerTimeContext returns database server time or error. The query is
+// aborted when ctx is done.
+func (db *DB) GetServerTimeContext(ctx context.Context) (time.Time, error) {
 	var st time.Time
 
 	if db.db == nil {
 		return st, ErrLostConnection
 	}
 
-	if _, err := db.db.QueryOne(pg.Scan(&st), "SELECT now()"); err != nil {
+	if _, err := db.db.WithContext(ctx).QueryOne(pg.Scan(&st), "SELECT now()"); err != nil {
 		return st, fmt.Errorf("postgres: %w", err)
 	}
 
 	return st, nil
 }
 
+// Exec executes the query which returns no rows.
+func (db *DB) Exec(query interface{}, params ...interface{}) (pg.Result, error) {
+	return db.ExecContext(context.Background(), query, params...)
+}
+
+// ExecContext executes the query which returns no rows. The query is aborted
+// when ctx is done.
+func (db *DB) ExecContext(ctx context.Context, query interface{}, params ...interface{}) (pg.Result, error) {
+	if db.db == nil {
+		return nil, ErrLostConnection
+	}
+
+	res, err := db.db.ExecContext(ctx, query, params...)
+	if err != nil {
+		return res, fmt.Errorf("postgres: %w", err)
+	}
+
+	return res, nil
+}
+
+// Query executes the query and scans returned rows to model.
+func (db *DB) Query(model, query interface{}, params ...interface{}) (pg.Result, error) {
+	return db.QueryContext(context.Background(), model, query, params...)
+}
+
+// QueryContext executes the query and scans returned rows to model. The query
+// is aborted when ctx is done.
+func (db *DB) QueryContext(ctx context.Context, model, query interface{}, params ...interface{}) (pg.Result, error) {
+	if db.db == nil {
+		return nil, ErrLostConnection
+	}
+
+	res, err := db.db.QueryContext(ctx, model, query, params...)
+	if err != nil {
+		return res, fmt.Errorf("postgres: %w", err)
+	}
+
+	return res, nil
+}
+
+// QueryOne executes the query which returns exactly one row and scans it to
+// model. pg.ErrNoRows is returned if there are no rows.
+func (db *DB) QueryOne(model, query interface{}, params ...interface{}) (pg.Result, error) {
+	return db.QueryOneContext(context.Background(), model, query, params...)
+}
+
+// QueryOneContext executes the query as QueryOne does. The query is aborted
+// when ctx is done.
+func (db *DB) QueryOneContext(ctx context.Context, model, query interface{}, params ...interface{}) (pg.Result, error) {
+	if db.db == nil {
+		return nil, ErrLostConnection
+	}
+
+	res, err := db.db.QueryOneContext(ctx, model, query, params...)
+	if err != nil {
+		return res, fmt.Errorf("postgres: %w", err)
+	}
+
+	return res, nil
+}
+
 // Close closes database connections.
 func (db *DB) Close() error {
 	if db.db == nil {
@@ -2,19 +2,64 @@
 package rabbit
 
 import (
+	"context"
+	"errors"
+	"net"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/assembla/cony"
+	"github.com/streadway/amqp"
+)
+
+// ErrLostConnection is returned when connection to server was lost.
+var ErrLostConnection = errors.New("rabbitmq: connection is lost")
+
+const (
+	// DefaultDialTimeout is the connection timeout of the checks which context
+	// has no deadline.
+	DefaultDialTimeout = 30 * time.Second
+
+	// ErrorsBuffer is the capacity of the client errors channel.
+	ErrorsBuffer = 100
 )
 
 // PublishFunc describes the publish to RabbitMQ function.
 type PublishFunc func(*cony.Publisher)
 
+// Broker is the interface of the RabbitMQ broker used by the service. It is
+// implemented by Client and by the in-memory fake for unit tests.
+type Broker interface {
+	// Publish sends the message by the publisher from config by name.
+	Publish(publisher string, msg amqp.Publishing) error
+
+	// PublishContext sends the message by the publisher from config by name
+	// and stops waiting when ctx is done.
+	PublishContext(ctx context.Context, publisher string, msg amqp.Publishing) error
+
+	// Consume starts new consumer of the queue from config by name and
+	// returns its deliveries. Consumers of the same queue share its
+	// messages. The consumer is stopped and deliveries channel is closed
+	// when ctx is done, unacknowledged deliveries are returned to the queue.
+	Consume(ctx context.Context, consumer string) (<-chan amqp.Delivery, error)
+}
+
 // Client is a wrapper around cony.Client which keeps track of the RabbitMQ
-// connections.
+// connections. The connection is managed by the client loop which runs from
+// NewClient until Close.
 type Client struct {
 	config *Config
 	cony   *cony.Client
+	errs   chan error
+	stop   chan struct{}
+	done   chan struct{}
+	once   sync.Once
+
+	// mu guards publishers and qos of the server config which is changed
+	// by SetQos while consumers are created.
+	mu         sync.Mutex
+	publishers map[string]*cony.Publisher
 }
 
 // NewDB creates new connection to RabbitMQ using cony.
@@ -23,9 +68,38 @@ func NewClient(cfg *Config) (*Client, error) {
 		return nil, err
 	}
 
-	client := cony.NewClient(cony.URL(cfg.Server.Server), cony.Backoff(cony.DefaultBackoff))
+	client := Client{
+		config:     cfg,
+		cony:       cony.NewClient(cony.URL(cfg.Server.Server), cony.Backoff(cony.DefaultBackoff)),
+		errs:       make(chan error, ErrorsBuffer),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+		publishers: make(map[string]*cony.Publisher),
+	}
+
+	go client.loop()
 
-	return &Client{config: cfg, cony: client}, nil
+	return &client, nil
+}
+
+// loop manages AMQP connection, runs declarations, consumers and publishers
+// and reconnects on failures until Close. Connection errors are sent to
+// Errors and are dropped if nobody reads them.
+func (client *Client) loop() {
+	defer close(client.done)
+	defer close(client.errs)
+
+	for client.cony.Loop() {
+		select {
+		case err := <-client.cony.Errors():
+			select {
+			case client.errs <- err:
+			default:
+			}
+		case <-client.stop:
+			return
+		}
+	}
 }
 
 // Dialer returns a pointer to the Dialer with which the connection was made.
@@ -33,25 +107,126 @@ func (client *Client) Config() *Config {
 	return client.config
 }
 
+// SetQos changes prefetch count of the consumers created after the call.
+func (client *Client) SetQos(qos int) {
+	client.mu.Lock()
+	client.config.Server.Qos = qos
+	client.mu.Unlock()
+}
+
+// qos returns current prefetch count of the consumers.
+func (client *Client) qos() int {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	return client.config.Server.Qos
+}
+
+// IsConnected checks availability of the server by opening and closing
+// a separate connection. It does not report state of the client connection:
+// connection of cony.Client is not exposed and is restored by cony in
+// background, its failures are sent to Errors. Each call costs a new AMQP
+// connection, so it must not be called on every request.
+func (client *Client) IsConnected() bool {
+	return client.IsConnectedContext(context.Background())
+}
+
+// IsConnectedContext checks availability of the server as IsConnected does.
+// Connection establishing and handshake are aborted when ctx is done.
+func (client *Client) IsConnectedContext(ctx context.Context) bool {
+	if client == nil || client.cony == nil {
+		return false
+	}
+
+	conn, err := amqp.DialConfig(client.config.Server.Server, amqp.Config{
+		Heartbeat: 10 * time.Second,
+		Locale:    "en_US",
+		Dial:      dialContext(ctx),
+	})
+	if err != nil {
+		return false
+	}
+
+	_ = conn.Close()
+
+	return true
+}
+
+// dialContext returns dial function bounded by ctx. The deadline is kept
+// for the handshake and is reset by amqp after it.
+func dialContext(ctx context.Context) func(network, addr string) (net.Conn, error) {
+	return func(network, addr string) (net.Conn, error) {
+		var dialer net.Dialer
+
+		conn, err := dialer.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			deadline = time.Now().Add(DefaultDialTimeout)
+		}
+
+		if err := conn.SetDeadline(deadline); err != nil {
+			_ = conn.Close()
+
+			return nil, err
+		}
+
+		return conn, nil
+	}
+}
+
+// Close stops the client loop, consumers and publishers. Publishes waiting
+// for the connection return cony.ErrPublisherDead. It is safe to call Close
+// several times.
+func (client *Client) Close() {
+	if client == nil || client.cony == nil {
+		return
+	}
+
+	client.once.Do(func() {
+		if client.stop != nil {
+			close(client.stop)
+		}
+
+		client.mu.Lock()
+		for _, pbl := range client.publishers {
+			pbl.Cancel()
+		}
+		client.mu.Unlock()
+
+		client.cony.Close()
+	})
+}
+
 // Cony returns pointer to cony.Client.
 func (client *Client) Cony() *cony.Client {
 	return client.cony
 }
 
-// Errors returns errors channel.
-// If you do not read the channel, then when its buffer is full, the client will
-// stop working.
+// Errors returns AMQP connection errors of the client loop. Errors are
+// dropped when the buffer of ErrorsBuffer errors is full, so reading the
+// channel is optional. The channel is closed when the loop is stopped by
+// Close.
 func (client *Client) Errors() <-chan error {
-	return client.cony.Errors()
+	return client.errs
 }
 
-// Loop returns true on successful receipt of data from the queue.
-// Loop should be run as condition for `for` with receiving from (*Client).Errors().
+// Loop reports whether the client loop is running, i.e. Close is not called.
 //
-// It will manage AMQP connection, run queue and exchange declarations, consumers.
-// Will start to return false once (*Client).Close() called.
+// Deprecated: the connection is managed by the loop started by NewClient and
+// Loop does not need to be called. It is kept for the code which runs
+// `for client.Loop() { <-client.Errors() }` and will be removed in the next
+// major version.
 func (client *Client) Loop() bool {
-	return client.cony.Loop()
+	select {
+	case <-client.done:
+		return false
+	default:
+		return true
+	}
 }
 
 // NewPublisherProcess creates new *cony.Publisher to exchange by routing_key
@@ -84,6 +259,92 @@ func (client *Client) NewPublisher(name string) (*cony.Publisher, error) {
 	return pbl, nil
 }
 
+// Publish sends the message by the publisher from config by name. Publisher is
+// created on the first call and reused by the next ones. Publish blocks until
+// the message is sent, i.e. while the client reconnects.
+func (client *Client) Publish(publisher string, msg amqp.Publishing) error {
+	return client.PublishContext(context.Background(), publisher, msg)
+}
+
+// PublishContext sends the message as Publish does but stops waiting when ctx
+// is done.
+//
+// cony cannot cancel the single publish, so it is left in the background
+// goroutine when ctx is done: the message is still sent when the connection
+// is restored, or the publish fails with cony.ErrPublisherDead on Close. Such
+// goroutines are kept while the server is unavailable, so publishes with
+// short deadlines to the lost server accumulate until reconnection or Close.
+func (client *Client) PublishContext(ctx context.Context, publisher string, msg amqp.Publishing) error {
+	pbl, err := client.publisher(publisher)
+	if err != nil {
+		return err
+	}
+
+	if ctx.Done() == nil {
+		return pbl.Publish(msg)
+	}
+
+	errs := make(chan error, 1)
+
+	go func() {
+		errs <- pbl.Publish(msg)
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// publisher returns cached publisher by name or creates new one. Publishers
+// are not created after Close because they would wait for the connection
+// forever.
+func (client *Client) publisher(name string) (*cony.Publisher, error) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	select {
+	case <-client.stop:
+		return nil, cony.ErrPublisherDead
+	default:
+	}
+
+	pbl, ok := client.publishers[name]
+	if !ok {
+		var err error
+		if pbl, err = client.NewPublisher(name); err != nil {
+			return nil, err
+		}
+
+		client.publishers[name] = pbl
+	}
+
+	return pbl, nil
+}
+
+// Consume creates new consumer from config by name and returns its
+// deliveries. The consumer is canceled when ctx is done or the client is
+// closed. Use NewConsumer to handle the consumer errors.
+func (client *Client) Consume(ctx context.Context, consumer string) (<-chan amqp.Delivery, error) {
+	cns, err := client.NewConsumer(consumer)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-client.done:
+		}
+
+		cns.Cancel()
+	}()
+
+	return cns.Deliveries(), nil
+}
+
 // NewConsumerBindTo creates new *cony.Consumer and binds to the queue
 // by routing_key.
 func (client *Client) NewConsumer(name string, opts ...cony.ConsumerOpt) (*cony.Consumer, error) {
@@ -122,8 +383,8 @@ func (client *Client) NewConsumer(name string, opts ...cony.ConsumerOpt) (*cony.
 		client.cony.Declare(declares)
 	}
 
-	if client.config.Server.Qos != 0 {
-		opts = append(opts, cony.Qos(client.config.Server.Qos))
+	if qos := client.qos(); qos != 0 {
+		opts = append(opts, cony.Qos(qos))
 	}
 
 	cns := cony.NewConsumer(
@@ -2,8 +2,10 @@ package rabbit
 
 import (
 	"errors"
-	"fmt"
+	"sort"
 
+	"github.com/outdead/goservice/internal/utils/backoff"
+	"github.com/outdead/goservice/internal/utils/multierror"
 	"github.com/streadway/amqp"
 )
 
@@ -23,36 +25,84 @@ var (
 
 // Config contains credentials for RabbitMQ.
 type Config struct {
+	Disabled   bool                       `yaml:"disabled" json:"disabled"`
 	Server     ServerConfig               `yaml:"server" json:"server"`
 	Consumers  map[string]ConsumerConfig  `yaml:"consumers" json:"consumers"`
 	Publishers map[string]PublisherConfig `yaml:"publishers" json:"publishers"`
+	Startup    backoff.RetryConfig        `yaml:"startup" json:"startup"`
+}
+
+// SetDefaults sets default values of the fields which are not set.
+func (cfg *Config) SetDefaults() {
+	cfg.Startup.SetDefaults()
+}
+
+// IsEnabled reports whether the connection is configured and not disabled.
+func (cfg *Config) IsEnabled() bool {
+	return cfg != nil && !cfg.Disabled
 }
 
 // Validate checks required fields and validates for allowed values.
 func (cfg *Config) Validate() error {
-	if err := cfg.Server.Validate(); err != nil {
-		return fmt.Errorf("server: %w", err)
+	if cfg.Disabled {
+		// Do not validate disabled connection.
+		return nil
 	}
 
+	errs := multierror.New()
+
+	errs.Append(multierror.Prefix(cfg.Server.Validate(), "server"))
+
 	if cfg.Consumers == nil {
-		return fmt.Errorf("consumers: %w", ErrNoConsumers)
+		errs.Append(multierror.Field("consumers", ErrNoConsumers))
 	}
 
-	for name, consumer := range cfg.Consumers {
-		if err := consumer.Validate(); err != nil {
-			return fmt.Errorf("consumers.%s: %w", name, err)
-		}
+	for _, name := range sortedKeys(cfg.Consumers) {
+		consumer := cfg.Consumers[name]
+		errs.Append(multierror.Prefix(consumer.Validate(), "consumers."+name))
+	}
+
+	for _, name := range sortedKeys(cfg.Publishers) {
+		publisher := cfg.Publishers[name]
+		errs.Append(multierror.Prefix(publisher.Validate(), "publishers."+name))
+	}
+
+	errs.Append(multierror.Prefix(cfg.Startup.Validate(), "startup"))
+
+	if errs.Len() != 0 {
+		return errs
 	}
 
 	return nil
 }
 
+// sortedKeys returns keys of consumers or publishers map in order to report
+// validation errors in stable order.
+func sortedKeys(m interface{}) []string {
+	var keys []string
+
+	switch m := m.(type) {
+	case map[string]ConsumerConfig:
+		for key := range m {
+			keys = append(keys, key)
+		}
+	case map[string]PublisherConfig:
+		for key := range m {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
 // Config contains credentials for RabbitMQ server.
 type ServerConfig struct {
-	Server   string `yaml:"server" json:"server"`
+	Server   string `yaml:"server" json:"server" secret:"url"`
 	Exchange struct {
 		Name       string `yaml:"name" json:"name"`
-		Type       string `yaml:"type" json:"type"`
+		Type       string `yaml:"type" json:"type" enum:"direct,fanout,topic,headers"`
 		AutoDelete bool   `yaml:"auto_delete" json:"auto_delete"`
 		Durable    bool   `yaml:"durable" json:"durable"`
 	} `yaml:"exchange" json:"exchange"`
@@ -69,18 +119,24 @@ type ServerConfig struct {
 
 // Validate checks required fields and validates for allowed values.
 func (cfg *ServerConfig) Validate() error {
+	errs := multierror.New()
+
 	if cfg.Server == "" {
-		return ErrEmptyServer
+		errs.Append(multierror.Field("server", ErrEmptyServer))
 	}
 
 	if cfg.Exchange.Type == "" {
-		return ErrEmptyExchangeType
+		errs.Append(multierror.Field("exchange.type", ErrEmptyExchangeType))
 	}
 
 	// If we want to publish, then routing_key must not be empty, if we want to
 	// consume, then queue.name must not be empty
 	if cfg.RoutingKey == "" && cfg.Queue.Name == "" {
-		return ErrEmptyRoutingKeyOrQueueName
+		errs.Append(ErrEmptyRoutingKeyOrQueueName)
+	}
+
+	if errs.Len() != 0 {
+		return errs
 	}
 
 	return nil
@@ -94,12 +150,18 @@ type ConsumerConfig struct {
 
 // Validate checks required fields and validates for allowed values.
 func (cfg *ConsumerConfig) Validate() error {
+	errs := multierror.New()
+
 	if cfg.QueueName == "" {
-		return ErrEmptyQueueName
+		errs.Append(multierror.Field("queue_name", ErrEmptyQueueName))
 	}
 
 	if cfg.RoutingKey == "" {
-		return ErrEmptyRoutingKey
+		errs.Append(multierror.Field("routing_key", ErrEmptyRoutingKey))
+	}
+
+	if errs.Len() != 0 {
+		return errs
 	}
 
 	return nil
@@ -113,12 +175,18 @@ type PublisherConfig struct {
 
 // Validate checks required fields and validates for allowed values.
 func (cfg *PublisherConfig) Validate() error {
+	errs := multierror.New()
+
 	if cfg.ExchangeName == "" {
-		return ErrEmptyExchangeName
+		errs.Append(multierror.Field("exchange_name", ErrEmptyExchangeName))
 	}
 
 	if cfg.RoutingKey == "" {
-		return ErrEmptyRoutingKey
+		errs.Append(multierror.Field("routing_key", ErrEmptyRoutingKey))
+	}
+
+	if errs.Len() != 0 {
+		return errs
 	}
 
 	return nil
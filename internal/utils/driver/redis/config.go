@@ -3,6 +3,9 @@ package redis
 import (
 	"errors"
 	"time"
+
+	"github.com/outdead/goservice/internal/utils/backoff"
+	"github.com/outdead/goservice/internal/utils/multierror"
 )
 
 // Config validation errors.
@@ -12,21 +15,46 @@ var (
 
 // Config contains credentials for Redis database.
 type Config struct {
-	Addr         string        `yaml:"addr"`
-	Password     string        `yaml:"password"`
-	DB           int           `yaml:"db"`
-	TTL          time.Duration `yaml:"ttl"`
-	MaxRetries   int           `yaml:"max_retries"`
-	DialTimeout  time.Duration `yaml:"dial_timeout"`
-	ReadTimeout  time.Duration `yaml:"read_timeout"`
-	WriteTimeout time.Duration `yaml:"write_timeout"`
-	PoolSize     int           `yaml:"pool_size"`
+	Disabled     bool                `yaml:"disabled"`
+	Addr         string              `yaml:"addr"`
+	Password     string              `yaml:"password" secret:"true"`
+	DB           int                 `yaml:"db"`
+	TTL          time.Duration       `yaml:"ttl"`
+	MaxRetries   int                 `yaml:"max_retries"`
+	DialTimeout  time.Duration       `yaml:"dial_timeout"`
+	ReadTimeout  time.Duration       `yaml:"read_timeout"`
+	WriteTimeout time.Duration       `yaml:"write_timeout"`
+	PoolSize     int                 `yaml:"pool_size"`
+	Startup      backoff.RetryConfig `yaml:"startup"`
+}
+
+// SetDefaults sets default values of the fields which are not set.
+func (cfg *Config) SetDefaults() {
+	cfg.Startup.SetDefaults()
+}
+
+// IsEnabled reports whether the connection is configured and not disabled.
+func (cfg *Config) IsEnabled() bool {
+	return cfg != nil && !cfg.Disabled
 }
 
 // Validate checks required fields and validates for allowed values.
 func (cfg *Config) Validate() error {
+	if cfg.Disabled {
+		// Do not validate disabled connection.
+		return nil
+	}
+
+	errs := multierror.New()
+
 	if cfg.Addr == "" {
-		return ErrEmptyAddr
+		errs.Append(multierror.Field("addr", ErrEmptyAddr))
+	}
+
+	errs.Append(multierror.Prefix(cfg.Startup.Validate(), "startup"))
+
+	if errs.Len() != 0 {
+		return errs
 	}
 
 	return nil
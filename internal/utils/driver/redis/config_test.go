@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/outdead/goservice/internal/utils/backoff"
 	"github.com/outdead/goservice/internal/utils/driver/redis"
 )
 
@@ -27,6 +28,8 @@ func TestConfig_Validate(t *testing.T) {
 	}{
 		{"positive validation", config, false},
 		{"empty addr", redis.Config{}, true},
+		{"disabled", redis.Config{Disabled: true}, false},
+		{"negative startup max_wait", redis.Config{Addr: "127.0.0.1:6379", Startup: backoff.RetryConfig{MaxWait: -1}}, true},
 	}
 
 	for _, tt := range tests {
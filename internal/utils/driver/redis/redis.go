@@ -14,6 +14,18 @@ var ErrNoRows = errors.New("key not found")
 // ErrLostConnection is returned when connection to database was lost.
 var ErrLostConnection = errors.New("redis: connection is lost")
 
+// Store is the interface of the Redis key-value store used by the service.
+// It is implemented by Client and by the in-memory fake for unit tests.
+type Store interface {
+	Set(key string, data interface{}) error
+	SetContext(ctx context.Context, key string, data interface{}) error
+	Get(key string) (string, error)
+	GetContext(ctx context.Context, key string) (string, error)
+	Del(key string) error
+	DelContext(ctx context.Context, key string) error
+	IsErrNoRows(err error) bool
+}
+
 // Client is a database handle representing connection to Redis.
 type Client struct {
 	config *Config
@@ -51,11 +63,17 @@ func NewClient(cfg *Config) (*Client, error) {
 
 // IsConnected checks connection status to database.
 func (client *Client) IsConnected() bool {
+	return client.IsConnectedContext(context.Background())
+}
+
+// IsConnectedContext checks connection status to database. The check is
+// aborted when ctx is done.
+func (client *Client) IsConnectedContext(ctx context.Context) bool {
 	if client.conn == nil {
 		return false
 	}
 
-	if _, err := client.conn.Ping(context.Background()).Result(); err != nil {
+	if _, err := client.conn.Ping(ctx).Result(); err != nil {
 		return false
 	}
 
@@ -74,14 +92,26 @@ func (client *Client) Conn() *redis.Client {
 
 // Set sets value by key to Redis with ttl.
 func (client *Client) Set(key string, data interface{}) error {
-	cmd := client.conn.Set(context.Background(), key, data, client.config.TTL)
+	return client.SetContext(context.Background(), key, data)
+}
+
+// SetContext sets value by key to Redis with ttl. The command is aborted when
+// ctx is done.
+func (client *Client) SetContext(ctx context.Context, key string, data interface{}) error {
+	cmd := client.conn.Set(ctx, key, data, client.config.TTL)
 
 	return cmd.Err()
 }
 
 // GetValue gets value by key from Redis.
 func (client *Client) Get(key string) (string, error) {
-	val, err := client.conn.Get(context.Background(), key).Result()
+	return client.GetContext(context.Background(), key)
+}
+
+// GetContext gets value by key from Redis. The command is aborted when ctx is
+// done.
+func (client *Client) GetContext(ctx context.Context, key string) (string, error) {
+	val, err := client.conn.Get(ctx, key).Result()
 	if err == redis.Nil { //nolint // this is still required according to go-redis documentation
 		return "", ErrNoRows
 	} else if err != nil {
@@ -93,7 +123,13 @@ func (client *Client) Get(key string) (string, error) {
 
 // Del deletes value from Redis by key.
 func (client *Client) Del(key string) error {
-	cmd := client.conn.Del(context.Background(), key)
+	return client.DelContext(context.Background(), key)
+}
+
+// DelContext deletes value from Redis by key. The command is aborted when ctx
+// is done.
+func (client *Client) DelContext(ctx context.Context, key string) error {
+	cmd := client.conn.Del(ctx, key)
 
 	return cmd.Err()
 }
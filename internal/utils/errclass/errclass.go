@@ -0,0 +1,142 @@
+// Package errclass classifies errors by their impact on the service.
+package errclass
+
+import (
+	"errors"
+	"sync"
+)
+
+// Class describes how bad the error is for the service.
+type Class int
+
+// Error classes.
+const (
+	// Recoverable errors can be handled without termination of the service,
+	// e.g. by restart of the failed component. Unclassified errors are
+	// recoverable.
+	Recoverable Class = iota
+
+	// Transient errors are expected to disappear on their own, e.g. response
+	// write error of the client which went away. They are only logged.
+	Transient
+
+	// Fatal errors always lead to the service termination.
+	Fatal
+)
+
+// Classes contains all known error classes.
+var Classes = []Class{Recoverable, Transient, Fatal}
+
+// String returns class name.
+func (c Class) String() string {
+	switch c {
+	case Recoverable:
+		return "recoverable"
+	case Transient:
+		return "transient"
+	case Fatal:
+		return "fatal"
+	default:
+		return "unknown"
+	}
+}
+
+// Error is an error with class.
+type Error struct {
+	class Class
+	err   error
+}
+
+// Wrap returns err with class. Returns nil if err is nil.
+func Wrap(err error, class Class) error {
+	if err == nil {
+		return nil
+	}
+
+	return &Error{class: class, err: err}
+}
+
+// Error implements error interface.
+func (e *Error) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the original error.
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// Class returns error class.
+func (e *Error) Class() Class {
+	return e.class
+}
+
+// Of returns class of the error. The first classified error in the err chain
+// is used. Unclassified errors are Recoverable.
+func Of(err error) Class {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.class
+	}
+
+	return Recoverable
+}
+
+// Counter counts errors by source and class. Counter is safe for concurrent
+// use.
+type Counter struct {
+	mu     sync.RWMutex
+	counts map[string]map[Class]int64
+}
+
+// NewCounter creates and returns new Counter.
+func NewCounter() *Counter {
+	return &Counter{counts: make(map[string]map[Class]int64)}
+}
+
+// Inc increases counter of errors of the class received from the source.
+func (c *Counter) Inc(source string, class Class) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.counts[source]; !ok {
+		c.counts[source] = make(map[Class]int64)
+	}
+
+	c.counts[source][class]++
+}
+
+// Stats contains errors counters.
+type Stats struct {
+	// Classes contains total counters by error classes.
+	Classes map[string]int64 `json:"classes"`
+
+	// Sources contains counters by error classes for each source.
+	Sources map[string]map[string]int64 `json:"sources"`
+}
+
+// Stats returns snapshot of counters.
+func (c *Counter) Stats() Stats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	stats := Stats{
+		Classes: make(map[string]int64, len(Classes)),
+		Sources: make(map[string]map[string]int64, len(c.counts)),
+	}
+
+	for _, class := range Classes {
+		stats.Classes[class.String()] = 0
+	}
+
+	for source, counts := range c.counts {
+		stats.Sources[source] = make(map[string]int64, len(counts))
+
+		for class, count := range counts {
+			stats.Sources[source][class.String()] = count
+			stats.Classes[class.String()] += count
+		}
+	}
+
+	return stats
+}
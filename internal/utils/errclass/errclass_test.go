@@ -0,0 +1,60 @@
+package errclass_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/outdead/goservice/internal/utils/errclass"
+)
+
+func TestOf(t *testing.T) {
+	errBase := errors.New("base error")
+
+	tests := []struct {
+		name string
+		err  error
+		want errclass.Class
+	}{
+		{"unclassified", errBase, errclass.Recoverable},
+		{"transient", errclass.Wrap(errBase, errclass.Transient), errclass.Transient},
+		{"wrapped fatal", fmt.Errorf("wrap: %w", errclass.Wrap(errBase, errclass.Fatal)), errclass.Fatal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errclass.Of(tt.err); got != tt.want {
+				t.Errorf("class expected: %s, got %s", tt.want, got)
+			}
+
+			if !errors.Is(tt.err, errBase) {
+				t.Errorf("classified error must wrap the original error")
+			}
+		})
+	}
+
+	if err := errclass.Wrap(nil, errclass.Fatal); err != nil {
+		t.Errorf("wrap nil expected nil, got %v", err)
+	}
+}
+
+func TestCounter_Stats(t *testing.T) {
+	counter := errclass.NewCounter()
+	counter.Inc("http", errclass.Recoverable)
+	counter.Inc("http", errclass.Recoverable)
+	counter.Inc("connector", errclass.Transient)
+
+	stats := counter.Stats()
+
+	if got := stats.Classes["recoverable"]; got != 2 {
+		t.Errorf("recoverable errors expected: %d, got %d", 2, got)
+	}
+
+	if got := stats.Classes["fatal"]; got != 0 {
+		t.Errorf("fatal errors expected: %d, got %d", 0, got)
+	}
+
+	if got := stats.Sources["connector"]["transient"]; got != 1 {
+		t.Errorf("connector transient errors expected: %d, got %d", 1, got)
+	}
+}
@@ -0,0 +1,32 @@
+package leader_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/outdead/goservice/internal/connector/fake"
+	"github.com/outdead/goservice/internal/utils/driver/postgres"
+	"github.com/outdead/goservice/internal/utils/driver/redis"
+	"github.com/outdead/goservice/internal/utils/leader"
+)
+
+func TestBackend_UnsupportedConnection(t *testing.T) {
+	tests := []struct {
+		name    string
+		backend leader.Backend
+	}{
+		{"postgres", leader.NewPostgresBackend(func() (postgres.Database, error) { return fake.NewPostgres(), nil }, "test")},
+		{"redis", leader.NewRedisBackend(func() (redis.Store, error) { return fake.NewRedis(), nil }, "test", time.Second)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, err := tt.backend.Acquire(context.Background())
+			if ok || !errors.Is(err, leader.ErrUnsupportedConnection) {
+				t.Errorf("expected %v, got %v, %v", leader.ErrUnsupportedConnection, ok, err)
+			}
+		})
+	}
+}
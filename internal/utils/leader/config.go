@@ -0,0 +1,87 @@
+package leader
+
+import (
+	"errors"
+	"time"
+
+	"github.com/outdead/goservice/internal/utils/multierror"
+)
+
+// Supported backends.
+const (
+	BackendPostgres = "postgres"
+	BackendRedis    = "redis"
+)
+
+// Default election settings are used when the corresponding config value is
+// not set. Default TTL is DefaultTTLIntervals of the configured interval.
+const (
+	DefaultInterval     = 5 * time.Second
+	DefaultTTLIntervals = 3
+)
+
+// Config validation errors.
+var (
+	ErrInvalidBackend  = errors.New("backend must be postgres or redis")
+	ErrEmptyKey        = errors.New("key is empty")
+	ErrInvalidInterval = errors.New("interval must be positive number")
+	ErrInvalidTTL      = errors.New("ttl must be greater than interval")
+)
+
+// Config contains leader election settings.
+type Config struct {
+	Enabled bool   `yaml:"enabled" json:"enabled"`
+	Backend string `yaml:"backend" json:"backend" enum:"postgres,redis"`
+	// Key identifies the lock. Replicas with the same key compete for
+	// the leadership.
+	Key string `yaml:"key" json:"key"`
+	// Interval is the period of the lock acquisition attempts and the held
+	// lock checks.
+	Interval time.Duration `yaml:"interval" json:"interval"`
+	// TTL is the lock lifetime in Redis. If the leader does not prolong the
+	// lock during TTL, another replica takes the leadership.
+	TTL time.Duration `yaml:"ttl" json:"ttl"`
+}
+
+// SetDefaults sets default values of the fields which are not set.
+func (cfg *Config) SetDefaults() {
+	if cfg.Interval == 0 {
+		cfg.Interval = DefaultInterval
+	}
+
+	if cfg.TTL == 0 {
+		cfg.TTL = DefaultTTLIntervals * cfg.Interval
+	}
+}
+
+// Validate checks required fields and validates for allowed values.
+func (cfg *Config) Validate() error {
+	if !cfg.Enabled {
+		// Do not validate disabled component.
+		return nil
+	}
+
+	errs := multierror.New()
+
+	if cfg.Backend != BackendPostgres && cfg.Backend != BackendRedis {
+		errs.Append(multierror.Field("backend", ErrInvalidBackend))
+	}
+
+	if cfg.Key == "" {
+		errs.Append(multierror.Field("key", ErrEmptyKey))
+	}
+
+	if cfg.Interval <= 0 {
+		errs.Append(multierror.Field("interval", ErrInvalidInterval))
+	}
+
+	if cfg.Backend == BackendRedis && cfg.TTL <= cfg.Interval {
+		errs.Append(multierror.Field("ttl", ErrInvalidTTL))
+	}
+
+	if errs.Len() != 0 {
+		return errs
+	}
+
+	return nil
+}
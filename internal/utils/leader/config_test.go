@@ -0,0 +1,82 @@
+package leader_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/outdead/goservice/internal/utils/leader"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  leader.Config
+		wantErr bool
+	}{
+		{"positive postgres validation", leader.Config{
+			Enabled:  true,
+			Backend:  leader.BackendPostgres,
+			Key:      "goservice",
+			Interval: time.Second,
+		}, false},
+		{"positive redis validation", leader.Config{
+			Enabled:  true,
+			Backend:  leader.BackendRedis,
+			Key:      "goservice",
+			Interval: time.Second,
+			TTL:      5 * time.Second,
+		}, false},
+		{"disabled config", leader.Config{}, false},
+		{"invalid backend", leader.Config{Enabled: true, Backend: "etcd"}, true},
+		{"empty key", leader.Config{Enabled: true, Backend: leader.BackendPostgres}, true},
+		{"empty interval", leader.Config{Enabled: true, Backend: leader.BackendPostgres, Key: "goservice"}, true},
+		{"small ttl", leader.Config{
+			Enabled:  true,
+			Backend:  leader.BackendRedis,
+			Key:      "goservice",
+			Interval: time.Second,
+			TTL:      time.Second,
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validation error expected: %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestConfig_SetDefaults(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   leader.Config
+		interval time.Duration
+		ttl      time.Duration
+	}{
+		{"empty config", leader.Config{}, leader.DefaultInterval, leader.DefaultTTLIntervals * leader.DefaultInterval},
+		{"ttl derived from interval", leader.Config{Interval: 15 * time.Second}, 15 * time.Second, 45 * time.Second},
+		{"ttl is kept", leader.Config{Interval: time.Second, TTL: 2 * time.Second}, time.Second, 2 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.config
+			cfg.SetDefaults()
+
+			if cfg.Interval != tt.interval {
+				t.Errorf("interval expected: %s, got %s", tt.interval, cfg.Interval)
+			}
+
+			if cfg.TTL != tt.ttl {
+				t.Errorf("ttl expected: %s, got %s", tt.ttl, cfg.TTL)
+			}
+
+			cfg.Enabled, cfg.Backend, cfg.Key = true, leader.BackendRedis, "goservice"
+			if err := cfg.Validate(); err != nil {
+				t.Errorf("config with defaults must be valid, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,268 @@
+// Package leader implements leader election between service replicas, so
+// singleton background work runs on exactly one of them.
+package leader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/outdead/goservice/internal/utils/errclass"
+	"github.com/outdead/goservice/internal/utils/logutil"
+)
+
+// Elector errors.
+var (
+	// ErrEmptyBackend is returned on start of Elector without backend.
+	ErrEmptyBackend = errors.New("leader election backend is not set")
+
+	// ErrUnsupportedConnection is returned by backends when the connection
+	// does not expose the client the lock is taken by, e.g. the fake.
+	ErrUnsupportedConnection = errors.New("connection does not support leader election")
+)
+
+// Backend acquires and keeps the leadership lock.
+type Backend interface {
+	// Acquire tries to take the lock. Returns false if the lock is held by
+	// another replica.
+	Acquire(ctx context.Context) (bool, error)
+
+	// Refresh checks and prolongs the held lock. Returns false if the lock
+	// is lost.
+	Refresh(ctx context.Context) (bool, error)
+
+	// Release releases the held lock.
+	Release(ctx context.Context) error
+}
+
+// Runner describes routines which are run while the replica is the leader,
+// e.g. tickerprocess.Process.
+type Runner interface {
+	Run()
+	Quit()
+}
+
+// Elector competes for the leadership with other replicas and calls hooks on
+// the leadership change. Elector implements daemon.Component interface.
+type Elector struct {
+	config  *Config
+	backend Backend
+	logger  *logutil.Entry
+	errors  chan error
+
+	// ctx aborts lock operations of the competition when it is done.
+	ctx context.Context
+
+	leader int32
+
+	mu        sync.Mutex
+	onElected []func()
+	onRevoked []func()
+
+	// Sync.
+	quit chan bool
+	wg   sync.WaitGroup
+}
+
+// NewElector creates and returns new Elector. Backend can be set later by
+// SetBackend before the start.
+func NewElector(cfg *Config, backend Backend, log *logutil.Entry) *Elector {
+	return &Elector{
+		config:  cfg,
+		backend: backend,
+		logger:  log,
+		errors:  make(chan error, 100),
+		ctx:     context.Background(),
+	}
+}
+
+// SetContext injects context of the competition, e.g. the service context
+// which is canceled on shutdown. Lock operations in progress are aborted when
+// ctx is done.
+func (e *Elector) SetContext(ctx context.Context) {
+	e.ctx = ctx
+}
+
+// SetBackend injects lock backend.
+func (e *Elector) SetBackend(backend Backend) {
+	e.backend = backend
+}
+
+// Name returns component name.
+func (e *Elector) Name() string {
+	return "leader"
+}
+
+// Errors returns errors channel.
+func (e *Elector) Errors() <-chan error {
+	return e.errors
+}
+
+// IsLeader returns true if the replica holds the leadership.
+func (e *Elector) IsLeader() bool {
+	return atomic.LoadInt32(&e.leader) == 1
+}
+
+// OnElected registers fn which is called when the replica becomes the leader.
+func (e *Elector) OnElected(fn func()) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.onElected = append(e.onElected, fn)
+}
+
+// OnRevoked registers fn which is called when the replica loses the leadership
+// or Elector is stopped.
+func (e *Elector) OnRevoked(fn func()) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.onRevoked = append(e.onRevoked, fn)
+}
+
+// Bind runs r while the replica is the leader.
+func (e *Elector) Bind(r Runner) {
+	e.OnElected(r.Run)
+	e.OnRevoked(r.Quit)
+}
+
+// Start starts the leadership competition in a separate goroutine.
+func (e *Elector) Start() error {
+	if e.backend == nil {
+		return ErrEmptyBackend
+	}
+
+	e.quit = make(chan bool, 1)
+	e.wg.Add(1)
+
+	go e.run()
+
+	return nil
+}
+
+// Stop stops the competition and releases the leadership.
+func (e *Elector) Stop() error {
+	if e.quit == nil {
+		return nil
+	}
+
+	e.quit <- true
+	e.wg.Wait()
+	e.quit = nil
+
+	if !e.IsLeader() {
+		return nil
+	}
+
+	e.revoke()
+
+	// Release is not bound to the competition context: it is canceled on
+	// shutdown before Stop, and the held lock must be released anyway so
+	// another replica does not wait for its expiration.
+	ctx, cancel := context.WithTimeout(context.Background(), e.config.Interval)
+	defer cancel()
+
+	if err := e.backend.Release(ctx); err != nil {
+		return fmt.Errorf("release leadership: %w", err)
+	}
+
+	return nil
+}
+
+func (e *Elector) run() {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(e.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		e.tick()
+
+		select {
+		case <-ticker.C:
+		case <-e.quit:
+			return
+		}
+	}
+}
+
+// tick acquires the lock or checks the held one.
+func (e *Elector) tick() {
+	// Competition is stopped by the context, the held lock is released by
+	// Stop.
+	if e.ctx.Err() != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(e.ctx, e.config.Interval)
+	defer cancel()
+
+	if e.IsLeader() {
+		ok, err := e.backend.Refresh(ctx)
+		if e.ctx.Err() != nil {
+			return
+		}
+
+		if err != nil {
+			e.reportError(fmt.Errorf("refresh leadership: %w", err))
+		}
+
+		if !ok {
+			e.logger.Warn("leadership is lost")
+			e.revoke()
+		}
+
+		return
+	}
+
+	ok, err := e.backend.Acquire(ctx)
+	if err != nil {
+		e.reportError(fmt.Errorf("acquire leadership: %w", err))
+
+		return
+	}
+
+	if ok {
+		e.logger.Info("elected as leader")
+		e.elect()
+	}
+}
+
+func (e *Elector) elect() {
+	atomic.StoreInt32(&e.leader, 1)
+
+	e.mu.Lock()
+	hooks := e.onElected
+	e.mu.Unlock()
+
+	for _, fn := range hooks {
+		fn()
+	}
+}
+
+func (e *Elector) revoke() {
+	atomic.StoreInt32(&e.leader, 0)
+
+	e.mu.Lock()
+	hooks := e.onRevoked
+	e.mu.Unlock()
+
+	for _, fn := range hooks {
+		fn()
+	}
+}
+
+// reportError publishes transient error to the errors channel. Failed lock
+// operations are repeated on the next tick.
+func (e *Elector) reportError(err error) {
+	if err != nil {
+		select {
+		case e.errors <- errclass.Wrap(err, errclass.Transient):
+		default:
+			e.logger.Errorf("leader error channel is locked: %s", err)
+		}
+	}
+}
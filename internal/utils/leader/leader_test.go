@@ -0,0 +1,193 @@
+package leader_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/outdead/goservice/internal/utils/leader"
+	"github.com/outdead/goservice/internal/utils/logutil"
+)
+
+// FakeBackend is in-memory lock shared by electors.
+type FakeBackend struct {
+	mu    *sync.Mutex
+	owner *string
+	id    string
+}
+
+func NewFakeBackends(n int) []*FakeBackend {
+	var (
+		mu    sync.Mutex
+		owner string
+	)
+
+	backends := make([]*FakeBackend, n)
+	for i := range backends {
+		backends[i] = &FakeBackend{mu: &mu, owner: &owner, id: string(rune('a' + i))}
+	}
+
+	return backends
+}
+
+func (b *FakeBackend) Acquire(ctx context.Context) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if *b.owner != "" {
+		return false, nil
+	}
+
+	*b.owner = b.id
+
+	return true, nil
+}
+
+func (b *FakeBackend) Refresh(ctx context.Context) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return *b.owner == b.id, nil
+}
+
+func (b *FakeBackend) Release(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if *b.owner == b.id {
+		*b.owner = ""
+	}
+
+	return nil
+}
+
+// FakeRunner counts runs and quits.
+type FakeRunner struct {
+	mu    sync.Mutex
+	runs  int
+	quits int
+}
+
+func (r *FakeRunner) Run() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.runs++
+}
+
+func (r *FakeRunner) Quit() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.quits++
+}
+
+func TestElector_Failover(t *testing.T) {
+	cfg := leader.Config{Enabled: true, Backend: "fake", Key: "test", Interval: 10 * time.Millisecond}
+	log := logutil.NewDiscardLogger().NewEntry()
+	backends := NewFakeBackends(2)
+
+	first := leader.NewElector(&cfg, backends[0], log)
+	second := leader.NewElector(&cfg, backends[1], log)
+
+	runner := new(FakeRunner)
+	first.Bind(runner)
+
+	if err := first.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, first.IsLeader)
+
+	if err := second.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(3 * cfg.Interval)
+
+	if second.IsLeader() {
+		t.Fatal("second elector must not be leader while the first holds the lock")
+	}
+
+	// Stop of the leader releases the lock and the second elector takes it.
+	if err := first.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, second.IsLeader)
+
+	if err := second.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	if runner.runs != 1 || runner.quits != 1 {
+		t.Errorf("runner runs and quits expected: 1 and 1, got %d and %d", runner.runs, runner.quits)
+	}
+}
+
+func TestElector_Stop_Canceled(t *testing.T) {
+	cfg := leader.Config{Enabled: true, Backend: "fake", Key: "test", Interval: 10 * time.Millisecond}
+	log := logutil.NewDiscardLogger().NewEntry()
+	backends := NewFakeBackends(2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	elector := leader.NewElector(&cfg, backends[0], log)
+	elector.SetContext(ctx)
+
+	runner := new(FakeRunner)
+	elector.Bind(runner)
+
+	if err := elector.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, elector.IsLeader)
+
+	// Canceled competition keeps the leadership until Stop.
+	cancel()
+	time.Sleep(3 * cfg.Interval)
+
+	if !elector.IsLeader() {
+		t.Fatal("leadership must be kept until Stop")
+	}
+
+	if err := elector.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Stop releases the lock after the context is canceled.
+	if ok, _ := backends[1].Acquire(context.Background()); !ok {
+		t.Error("lock must be released by Stop")
+	}
+
+	if runner.runs != 1 || runner.quits != 1 {
+		t.Errorf("runner runs and quits expected: 1 and 1, got %d and %d", runner.runs, runner.quits)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition was not met in time")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,136 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sync"
+
+	"github.com/go-pg/pg/v9"
+	"github.com/outdead/goservice/internal/utils/driver/postgres"
+)
+
+// PostgresDB is the PostgreSQL database which exposes pg.DB for the session
+// level lock. It is implemented by postgres.DB.
+type PostgresDB interface {
+	DB() *pg.DB
+}
+
+// PostgresResolver returns the current PostgreSQL database. It is called on
+// every acquire, so databases replaced by reconnection or reload are picked
+// up. The held lock is bound to the session of the database it was taken on
+// and is lost with it.
+type PostgresResolver func() (postgres.Database, error)
+
+// PostgresBackend holds the leadership by PostgreSQL session level advisory
+// lock. The lock is released by the database when the leader dies and its
+// session is closed.
+type PostgresBackend struct {
+	db  PostgresResolver
+	key int64
+
+	mu   sync.Mutex
+	conn *pg.Conn
+}
+
+// NewPostgresBackend creates and returns new PostgresBackend. The key is
+// hashed to the advisory lock identifier.
+func NewPostgresBackend(db PostgresResolver, key string) *PostgresBackend {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+
+	return &PostgresBackend{db: db, key: int64(h.Sum64())}
+}
+
+// Acquire tries to take the advisory lock on a dedicated connection.
+func (b *PostgresBackend) Acquire(ctx context.Context) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	db, err := b.db()
+	if err != nil {
+		return false, fmt.Errorf("postgres: %w", err)
+	}
+
+	pdb, supported := db.(PostgresDB)
+	if !supported {
+		return false, fmt.Errorf("postgres: %w", ErrUnsupportedConnection)
+	}
+
+	// Session level lock belongs to the connection, so the connection is
+	// kept while the lock is held.
+	conn := pdb.DB().Conn()
+
+	var ok bool
+	if _, err := conn.QueryOneContext(ctx, pg.Scan(&ok), "SELECT pg_try_advisory_lock(?)", b.key); err != nil {
+		_ = conn.Close()
+
+		return false, fmt.Errorf("postgres: %w", err)
+	}
+
+	if !ok {
+		return false, conn.Close()
+	}
+
+	b.conn = conn
+
+	return true, nil
+}
+
+// Refresh checks that the advisory lock is still held by the session of the
+// dedicated connection.
+func (b *PostgresBackend) Refresh(ctx context.Context) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.conn == nil {
+		return false, nil
+	}
+
+	// The connection can be silently replaced in the pool after network
+	// failure, so the lock owner is checked by the backend pid.
+	query := `SELECT EXISTS (
+		SELECT 1 FROM pg_locks
+		WHERE locktype = 'advisory' AND classid = ? AND objid = ? AND objsubid = 1
+			AND pid = pg_backend_pid() AND granted
+	)`
+
+	var ok bool
+	if _, err := b.conn.QueryOneContext(ctx, pg.Scan(&ok), query, uint32(uint64(b.key)>>32), uint32(b.key)); err != nil {
+		b.closeConn()
+
+		return false, fmt.Errorf("postgres: %w", err)
+	}
+
+	if !ok {
+		b.closeConn()
+	}
+
+	return ok, nil
+}
+
+// Release releases the advisory lock and returns the dedicated connection to
+// the pool.
+func (b *PostgresBackend) Release(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.conn == nil {
+		return nil
+	}
+
+	defer b.closeConn()
+
+	if _, err := b.conn.ExecContext(ctx, "SELECT pg_advisory_unlock(?)", b.key); err != nil {
+		return fmt.Errorf("postgres: %w", err)
+	}
+
+	return nil
+}
+
+func (b *PostgresBackend) closeConn() {
+	// Closed session releases its advisory locks, so the close error can be
+	// ignored.
+	_ = b.conn.Close()
+	b.conn = nil
+}
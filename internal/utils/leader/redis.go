@@ -0,0 +1,116 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/outdead/goservice/internal/utils/driver/redis"
+)
+
+// Scripts check the lock owner before changing the lock.
+const (
+	redisRefreshScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`
+
+	redisReleaseScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`
+)
+
+// RedisClient is the Redis store which exposes redis.Client for the lock
+// commands. It is implemented by redis.Client.
+type RedisClient interface {
+	Conn() *goredis.Client
+}
+
+// RedisResolver returns the current Redis client. It is called on every lock
+// operation, so clients replaced by reconnection or reload are picked up.
+type RedisResolver func() (redis.Store, error)
+
+// RedisBackend holds the leadership by the Redis key with TTL. The key expires
+// when the leader dies and stops prolonging it.
+type RedisBackend struct {
+	client RedisResolver
+	key    string
+	ttl    time.Duration
+	id     string
+}
+
+// NewRedisBackend creates and returns new RedisBackend. The replica is
+// identified by the host name and the process id.
+func NewRedisBackend(client RedisResolver, key string, ttl time.Duration) *RedisBackend {
+	host, _ := os.Hostname()
+
+	return &RedisBackend{
+		client: client,
+		key:    key,
+		ttl:    ttl,
+		id:     host + ":" + strconv.Itoa(os.Getpid()) + ":" + strconv.FormatInt(time.Now().UnixNano(), 10),
+	}
+}
+
+// Acquire sets the key if it does not exist.
+func (b *RedisBackend) Acquire(ctx context.Context) (bool, error) {
+	client, err := b.resolve()
+	if err != nil {
+		return false, err
+	}
+
+	ok, err := client.SetNX(ctx, b.key, b.id, b.ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("redis: %w", err)
+	}
+
+	return ok, nil
+}
+
+// Refresh prolongs the key TTL if the key is owned by the replica.
+func (b *RedisBackend) Refresh(ctx context.Context) (bool, error) {
+	client, err := b.resolve()
+	if err != nil {
+		return false, err
+	}
+
+	res, err := client.Eval(ctx, redisRefreshScript, []string{b.key}, b.id, b.ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("redis: %w", err)
+	}
+
+	return res == 1, nil
+}
+
+// Release deletes the key if it is owned by the replica.
+func (b *RedisBackend) Release(ctx context.Context) error {
+	client, err := b.resolve()
+	if err != nil {
+		return err
+	}
+
+	if err := client.Eval(ctx, redisReleaseScript, []string{b.key}, b.id).Err(); err != nil {
+		return fmt.Errorf("redis: %w", err)
+	}
+
+	return nil
+}
+
+// resolve returns the current Redis client.
+func (b *RedisBackend) resolve() (*goredis.Client, error) {
+	store, err := b.client()
+	if err != nil {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+
+	client, ok := store.(RedisClient)
+	if !ok {
+		return nil, fmt.Errorf("redis: %w", ErrUnsupportedConnection)
+	}
+
+	return client.Conn(), nil
+}
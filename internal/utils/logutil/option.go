@@ -2,7 +2,14 @@ package logutil
 
 // Config contains Logger settings.
 type Config struct {
-	Level string `yaml:"level"`
+	Level string `yaml:"level" enum:"panic,fatal,error,warn,warning,info,debug,trace"`
+}
+
+// SetDefaults sets default values of the fields which are not set.
+func (cfg *Config) SetDefaults() {
+	if cfg.Level == "" {
+		cfg.Level = DefaultLogLevel.String()
+	}
 }
 
 // Option allows to inject options to Logger.
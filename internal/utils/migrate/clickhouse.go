@@ -0,0 +1,131 @@
+package migrate
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ClickhouseDB is the ClickHouse database which exposes sqlx.DB for
+// migrations. It is implemented by clickhouse.DB.
+type ClickhouseDB interface {
+	DB() *sqlx.DB
+	MultiInsertContext(ctx context.Context, query string, rows [][]interface{}) error
+}
+
+// ClickhouseDriver executes migrations on ClickHouse. ClickHouse has no
+// transactional DDL, so statements of the failed migration executed before
+// the error are not rolled back. Versions are stored as appended rows with
+// applied flag and the latest row of the version wins. Rows are ordered by
+// nanosecond timestamps, so apply and revert in the same second are not
+// mixed up. ClickHouse has no locks, use SetLocker of the Migrator to
+// serialize replicas.
+type ClickhouseDriver struct {
+	db ClickhouseDB
+}
+
+// NewClickhouseDriver creates new ClickhouseDriver.
+func NewClickhouseDriver(db ClickhouseDB) *ClickhouseDriver {
+	return &ClickhouseDriver{db: db}
+}
+
+// Init creates migrations table if it does not exist.
+func (d *ClickhouseDriver) Init(ctx context.Context) error {
+	_, err := d.db.DB().ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+Table+` (
+		version Int64,
+		name String,
+		applied UInt8,
+		applied_at DateTime64(9)
+	) ENGINE = MergeTree ORDER BY (version, applied_at)`)
+
+	return err
+}
+
+// Applied returns applied migrations sorted by version.
+func (d *ClickhouseDriver) Applied(ctx context.Context) ([]Record, error) {
+	rows, err := d.db.DB().QueryxContext(ctx, `SELECT version, argMax(name, applied_at), max(applied_at)
+		FROM `+Table+`
+		GROUP BY version
+		HAVING argMax(applied, applied_at) = 1
+		ORDER BY version`)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	return scanRecords(rows)
+}
+
+// Apply executes up migration and records the version.
+func (d *ClickhouseDriver) Apply(ctx context.Context, m *Migration) error {
+	if err := d.exec(ctx, m.Up); err != nil {
+		return err
+	}
+
+	return d.record(ctx, m, true)
+}
+
+// Revert executes down migration and records the version as reverted.
+func (d *ClickhouseDriver) Revert(ctx context.Context, m *Migration) error {
+	if err := d.exec(ctx, m.Down); err != nil {
+		return err
+	}
+
+	return d.record(ctx, m, false)
+}
+
+// exec executes statements of the migration one by one because ClickHouse
+// does not support multi-statement queries. Statements are separated by
+// semicolons at the end of a line.
+func (d *ClickhouseDriver) exec(ctx context.Context, query string) error {
+	for _, statement := range splitStatements(query) {
+		if _, err := d.db.DB().ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *ClickhouseDriver) record(ctx context.Context, m *Migration, applied bool) error {
+	var flag uint8
+	if applied {
+		flag = 1
+	}
+
+	return d.db.MultiInsertContext(ctx, `INSERT INTO `+Table+` (version, name, applied, applied_at) VALUES (?, ?, ?, ?)`,
+		[][]interface{}{{m.Version, m.Name, flag, time.Now()}})
+}
+
+func scanRecords(rows *sqlx.Rows) ([]Record, error) {
+	var records []Record
+
+	for rows.Next() {
+		var record Record
+		if err := rows.Scan(&record.Version, &record.Name, &record.AppliedAt); err != nil {
+			return nil, err
+		}
+
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
+
+// splitStatements splits query to statements by semicolons at the end of
+// a line. Empty statements are skipped.
+func splitStatements(query string) []string {
+	var statements []string
+
+	for _, statement := range strings.Split(query, ";\n") {
+		statement = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(statement), ";"))
+		if statement != "" {
+			statements = append(statements, statement)
+		}
+	}
+
+	return statements
+}
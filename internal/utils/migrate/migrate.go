@@ -0,0 +1,238 @@
+// Package migrate applies versioned SQL migrations and tracks applied versions
+// in the database table.
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/outdead/goservice/internal/utils/multierror"
+)
+
+// Table is the name of the table which contains applied migrations.
+const Table = "schema_migrations"
+
+// Migrator errors.
+var (
+	ErrIrreversible   = errors.New("migration is irreversible")
+	ErrUnknownVersion = errors.New("applied migration is not found")
+	ErrUnsupported    = errors.New("connection does not support migrations")
+)
+
+// Record describes applied migration.
+type Record struct {
+	Version   int64
+	Name      string
+	AppliedAt time.Time
+}
+
+// Driver executes migrations on the database. Queries are aborted when ctx
+// is done.
+type Driver interface {
+	// Init creates migrations table if it does not exist.
+	Init(ctx context.Context) error
+	// Applied returns applied migrations sorted by version.
+	Applied(ctx context.Context) ([]Record, error)
+	// Apply executes up migration and records the version.
+	Apply(ctx context.Context, m *Migration) error
+	// Revert executes down migration and removes the version record.
+	Revert(ctx context.Context, m *Migration) error
+}
+
+// Locker serializes migrations of the replicas started at the same time.
+// Drivers which implement Locker are locked by Migrator, other lockers are
+// set by SetLocker.
+type Locker interface {
+	// Lock blocks until the lock is taken or ctx is done.
+	Lock(ctx context.Context) error
+	// Unlock releases the lock. It has no context because the lock must be
+	// released when the migration is aborted by ctx.
+	Unlock() error
+}
+
+// Status describes state of the migration.
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Migrator applies and reverts migrations of one database.
+type Migrator struct {
+	name       string
+	driver     Driver
+	locker     Locker
+	migrations []Migration
+}
+
+// NewMigrator creates new Migrator. Migrations must be sorted by version.
+func NewMigrator(name string, driver Driver, migrations []Migration) *Migrator {
+	m := Migrator{name: name, driver: driver, migrations: migrations}
+
+	if locker, ok := driver.(Locker); ok {
+		m.locker = locker
+	}
+
+	return &m
+}
+
+// SetLocker sets lock taken by Up and Down, e.g. lock of another database
+// for the database which has no locks.
+func (m *Migrator) SetLocker(locker Locker) {
+	m.locker = locker
+}
+
+// Name returns database name.
+func (m *Migrator) Name() string {
+	return m.name
+}
+
+// Up applies pending migrations in order and returns applied ones. Migration
+// fails on the first error, migrations applied before are kept. Applied
+// versions are read under the lock, so replicas waiting for the lock skip
+// migrations applied by the lock holder. Migration is aborted when ctx is
+// done.
+func (m *Migrator) Up(ctx context.Context) (_ []Migration, err error) {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { err = unlock(err) }()
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+
+	for i := range m.migrations {
+		migration := &m.migrations[i]
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		if err := m.driver.Apply(ctx, migration); err != nil {
+			return done, fmt.Errorf("%s: apply %d_%s: %w", m.name, migration.Version, migration.Name, err)
+		}
+
+		done = append(done, *migration)
+	}
+
+	return done, nil
+}
+
+// Down reverts the last steps applied migrations in reverse order and returns
+// reverted ones. Migration is aborted when ctx is done.
+func (m *Migrator) Down(ctx context.Context, steps int) (_ []Migration, err error) {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { err = unlock(err) }()
+
+	records, err := m.records(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make(map[int64]*Migration, len(m.migrations))
+	for i := range m.migrations {
+		migrations[m.migrations[i].Version] = &m.migrations[i]
+	}
+
+	var done []Migration
+
+	for i := len(records) - 1; i >= 0 && len(done) < steps; i-- {
+		migration, ok := migrations[records[i].Version]
+		if !ok {
+			return done, fmt.Errorf("%s: revert %d_%s: %w", m.name, records[i].Version, records[i].Name, ErrUnknownVersion)
+		}
+
+		if migration.Down == "" {
+			return done, fmt.Errorf("%s: revert %d_%s: %w", m.name, migration.Version, migration.Name, ErrIrreversible)
+		}
+
+		if err := m.driver.Revert(ctx, migration); err != nil {
+			return done, fmt.Errorf("%s: revert %d_%s: %w", m.name, migration.Version, migration.Name, err)
+		}
+
+		done = append(done, *migration)
+	}
+
+	return done, nil
+}
+
+// Status returns state of the known migrations sorted by version.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+
+	for _, migration := range m.migrations {
+		record, ok := applied[migration.Version]
+		statuses = append(statuses, Status{Migration: migration, Applied: ok, AppliedAt: record.AppliedAt})
+	}
+
+	return statuses, nil
+}
+
+// lock takes the lock if it is set and returns function which releases it.
+// Unlock error is added to the migration error.
+func (m *Migrator) lock(ctx context.Context) (func(err error) error, error) {
+	if m.locker == nil {
+		return func(err error) error { return err }, nil
+	}
+
+	if err := m.locker.Lock(ctx); err != nil {
+		return nil, fmt.Errorf("%s: lock: %w", m.name, err)
+	}
+
+	return func(err error) error {
+		uerr := m.locker.Unlock()
+		if uerr == nil {
+			return err
+		}
+
+		uerr = fmt.Errorf("%s: unlock: %w", m.name, uerr)
+		if err == nil {
+			return uerr
+		}
+
+		return multierror.New(err, uerr)
+	}, nil
+}
+
+func (m *Migrator) records(ctx context.Context) ([]Record, error) {
+	if err := m.driver.Init(ctx); err != nil {
+		return nil, fmt.Errorf("%s: init: %w", m.name, err)
+	}
+
+	records, err := m.driver.Applied(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: applied migrations: %w", m.name, err)
+	}
+
+	return records, nil
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[int64]Record, error) {
+	records, err := m.records(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int64]Record, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+
+	return applied, nil
+}
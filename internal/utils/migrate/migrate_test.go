@@ -0,0 +1,264 @@
+package migrate_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/outdead/goservice/internal/utils/migrate"
+)
+
+var (
+	errApply  = errors.New("apply error")
+	errUnlock = errors.New("unlock error")
+)
+
+// FakeDriver keeps applied migrations in memory.
+type FakeDriver struct {
+	records []migrate.Record
+	fail    int64
+}
+
+func (d *FakeDriver) Init(_ context.Context) error {
+	return nil
+}
+
+func (d *FakeDriver) Applied(_ context.Context) ([]migrate.Record, error) {
+	return d.records, nil
+}
+
+func (d *FakeDriver) Apply(_ context.Context, m *migrate.Migration) error {
+	if m.Version == d.fail {
+		return errApply
+	}
+
+	d.records = append(d.records, migrate.Record{Version: m.Version, Name: m.Name, AppliedAt: time.Now()})
+
+	return nil
+}
+
+func (d *FakeDriver) Revert(_ context.Context, m *migrate.Migration) error {
+	for i := range d.records {
+		if d.records[i].Version == m.Version {
+			d.records = append(d.records[:i], d.records[i+1:]...)
+
+			break
+		}
+	}
+
+	return nil
+}
+
+// FakeLocker is the in-process lock shared by migrators of the replicas.
+type FakeLocker struct {
+	sem    chan struct{}
+	locked bool
+	fail   bool
+}
+
+func NewFakeLocker() *FakeLocker {
+	return &FakeLocker{sem: make(chan struct{}, 1)}
+}
+
+func (l *FakeLocker) Lock(ctx context.Context) error {
+	select {
+	case l.sem <- struct{}{}:
+		l.locked = true
+
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *FakeLocker) Unlock() error {
+	l.locked = false
+	<-l.sem
+
+	if l.fail {
+		return errUnlock
+	}
+
+	return nil
+}
+
+// LockedDriver fails migrations executed without the lock.
+type LockedDriver struct {
+	FakeDriver
+	locker *FakeLocker
+}
+
+func (d *LockedDriver) Apply(ctx context.Context, m *migrate.Migration) error {
+	if !d.locker.locked {
+		return errors.New("apply without lock")
+	}
+
+	return d.FakeDriver.Apply(ctx, m)
+}
+
+var migrations = []migrate.Migration{
+	{Version: 1, Name: "create_table", Up: "CREATE TABLE", Down: "DROP TABLE"},
+	{Version: 2, Name: "add_column", Up: "ALTER TABLE ADD", Down: "ALTER TABLE DROP"},
+	{Version: 3, Name: "add_index", Up: "CREATE INDEX"},
+}
+
+func TestMigrator_Up(t *testing.T) {
+	driver := FakeDriver{records: []migrate.Record{{Version: 1, Name: "create_table"}}}
+	m := migrate.NewMigrator("postgres", &driver, migrations)
+
+	applied, err := m.Up(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(applied) != 2 || applied[0].Version != 2 || applied[1].Version != 3 {
+		t.Errorf("expected migrations 2 and 3 applied, got %+v", applied)
+	}
+
+	if applied, err = m.Up(context.Background()); err != nil || len(applied) != 0 {
+		t.Errorf("expected no pending migrations, got %+v, %v", applied, err)
+	}
+}
+
+func TestMigrator_Up_Error(t *testing.T) {
+	driver := FakeDriver{fail: 2}
+	m := migrate.NewMigrator("postgres", &driver, migrations)
+
+	applied, err := m.Up(context.Background())
+	if !errors.Is(err, errApply) {
+		t.Errorf("expected error %v, got %v", errApply, err)
+	}
+
+	if len(applied) != 1 || applied[0].Version != 1 {
+		t.Errorf("expected migration 1 applied, got %+v", applied)
+	}
+}
+
+func TestMigrator_Down(t *testing.T) {
+	driver := FakeDriver{}
+	m := migrate.NewMigrator("postgres", &driver, migrations)
+
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if _, err := m.Down(context.Background(), 1); !errors.Is(err, migrate.ErrIrreversible) {
+		t.Errorf("expected error %v, got %v", migrate.ErrIrreversible, err)
+	}
+
+	m = migrate.NewMigrator("postgres", &driver, migrations[:2])
+	driver.records = driver.records[:2]
+
+	reverted, err := m.Down(context.Background(), 5)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(reverted) != 2 || reverted[0].Version != 2 || reverted[1].Version != 1 {
+		t.Errorf("expected migrations 2 and 1 reverted, got %+v", reverted)
+	}
+}
+
+func TestMigrator_Status(t *testing.T) {
+	driver := FakeDriver{records: []migrate.Record{{Version: 2, Name: "add_column"}}}
+	m := migrate.NewMigrator("postgres", &driver, migrations)
+
+	statuses, err := m.Status(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for i, want := range []bool{false, true, false} {
+		if statuses[i].Applied != want {
+			t.Errorf("migration %d: expected applied %t, got %t", statuses[i].Version, want, statuses[i].Applied)
+		}
+	}
+}
+
+func TestMigrator_Up_Lock(t *testing.T) {
+	locker := NewFakeLocker()
+	driver := LockedDriver{locker: locker}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		applied int
+	)
+
+	// Replicas started at the same time apply each migration once.
+	for i := 0; i < 3; i++ {
+		m := migrate.NewMigrator("clickhouse", &driver, migrations)
+		m.SetLocker(locker)
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			done, err := m.Up(context.Background())
+			if err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+
+			mu.Lock()
+			applied += len(done)
+			mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+
+	if applied != len(migrations) || len(driver.records) != len(migrations) {
+		t.Errorf("expected %d migrations applied once, got %d applied and %d records", len(migrations), applied, len(driver.records))
+	}
+
+	if locker.locked {
+		t.Error("expected lock released")
+	}
+}
+
+func TestMigrator_Up_Unlock_Error(t *testing.T) {
+	locker := NewFakeLocker()
+	locker.fail = true
+	driver := LockedDriver{locker: locker, FakeDriver: FakeDriver{fail: 2}}
+
+	m := migrate.NewMigrator("clickhouse", &driver, migrations)
+	m.SetLocker(locker)
+
+	// Unlock error is added to the migration error.
+	_, err := m.Up(context.Background())
+	if err == nil || err.Error() != "clickhouse: apply 2_add_column: apply error, clickhouse: unlock: unlock error" {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if _, err := m.Down(context.Background(), 1); !errors.Is(err, errUnlock) {
+		t.Errorf("expected error %v, got %v", errUnlock, err)
+	}
+}
+
+func TestMigrator_Up_Canceled(t *testing.T) {
+	locker := NewFakeLocker()
+	driver := LockedDriver{locker: locker}
+
+	m := migrate.NewMigrator("clickhouse", &driver, migrations)
+	m.SetLocker(locker)
+
+	// The lock is held by another replica.
+	if err := locker.Lock(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	// Waiting for the lock is aborted by ctx.
+	if _, err := m.Up(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected error %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	if len(driver.records) != 0 {
+		t.Errorf("expected no migrations applied, got %+v", driver.records)
+	}
+}
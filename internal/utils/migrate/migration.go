@@ -0,0 +1,94 @@
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// Migration loading errors.
+var (
+	ErrInvalidFileName    = errors.New("migration file name must be <version>_<name>.up.sql or <version>_<name>.down.sql")
+	ErrDuplicateVersion   = errors.New("duplicate migration version")
+	ErrMissingUpMigration = errors.New("up migration is missing")
+)
+
+// fileName matches migration file names, e.g. 0001_create_users.up.sql.
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration contains versioned SQL queries which change database schema.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	// Down is empty if the migration is irreversible.
+	Down string
+}
+
+// Load reads migrations from the dir of fsys. Migrations are sorted by
+// version. Files without .sql extension are ignored.
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("read migrations: %w", err)
+	}
+
+	migrations := make(map[int64]*Migration)
+
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+
+		matches := fileName.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), ErrInvalidFileName)
+		}
+
+		version, err := strconv.ParseInt(matches[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), ErrInvalidFileName)
+		}
+
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("read migration: %w", err)
+		}
+
+		m, ok := migrations[version]
+		if !ok {
+			m = &Migration{Version: version, Name: matches[2]}
+			migrations[version] = m
+		}
+
+		if m.Name != matches[2] {
+			return nil, fmt.Errorf("%s: %w %d", entry.Name(), ErrDuplicateVersion, version)
+		}
+
+		if matches[3] == "up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	list := make([]Migration, 0, len(migrations))
+
+	for _, m := range migrations {
+		if m.Up == "" {
+			return nil, fmt.Errorf("%d_%s: %w", m.Version, m.Name, ErrMissingUpMigration)
+		}
+
+		list = append(list, *m)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Version < list[j].Version
+	})
+
+	return list, nil
+}
@@ -0,0 +1,65 @@
+package migrate_test
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/outdead/goservice/internal/utils/migrate"
+)
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"db/0002_add_index.up.sql":      {Data: []byte("CREATE INDEX")},
+		"db/0001_create_table.up.sql":   {Data: []byte("CREATE TABLE")},
+		"db/0001_create_table.down.sql": {Data: []byte("DROP TABLE")},
+		"db/README.md":                  {Data: []byte("docs")},
+	}
+
+	migrations, err := migrate.Load(fsys, "db")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	want := []migrate.Migration{
+		{Version: 1, Name: "create_table", Up: "CREATE TABLE", Down: "DROP TABLE"},
+		{Version: 2, Name: "add_index", Up: "CREATE INDEX"},
+	}
+
+	if len(migrations) != len(want) {
+		t.Fatalf("expected %d migrations, got %d", len(want), len(migrations))
+	}
+
+	for i := range want {
+		if migrations[i] != want[i] {
+			t.Errorf("expected %+v, got %+v", want[i], migrations[i])
+		}
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+		want error
+	}{
+		{"invalid file name", fstest.MapFS{
+			"db/create_table.up.sql": {Data: []byte("CREATE TABLE")},
+		}, migrate.ErrInvalidFileName},
+		{"duplicate version", fstest.MapFS{
+			"db/0001_create_table.up.sql": {Data: []byte("CREATE TABLE")},
+			"db/0001_add_index.up.sql":    {Data: []byte("CREATE INDEX")},
+		}, migrate.ErrDuplicateVersion},
+		{"missing up migration", fstest.MapFS{
+			"db/0001_create_table.down.sql": {Data: []byte("DROP TABLE")},
+		}, migrate.ErrMissingUpMigration},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := migrate.Load(tt.fsys, "db"); !errors.Is(err, tt.want) {
+				t.Errorf("expected error %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sync"
+
+	"github.com/go-pg/pg/v9"
+)
+
+// PostgresDB is the PostgreSQL database which exposes pg.DB for migrations.
+// It is implemented by postgres.DB.
+type PostgresDB interface {
+	DB() *pg.DB
+}
+
+// PostgresDriver executes migrations on PostgreSQL. Each migration is
+// executed in transaction with its version record. Migrations of the
+// replicas are serialized by session level advisory lock.
+type PostgresDriver struct {
+	db  *pg.DB
+	key int64
+
+	mu   sync.Mutex
+	conn *pg.Conn
+}
+
+// NewPostgresDriver creates new PostgresDriver.
+func NewPostgresDriver(db PostgresDB) *PostgresDriver {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(Table))
+
+	return &PostgresDriver{db: db.DB(), key: int64(h.Sum64())}
+}
+
+// Lock waits for the advisory lock on a dedicated connection. The lock is
+// released by the database if the process dies while migrating. Waiting is
+// aborted when ctx is done.
+func (d *PostgresDriver) Lock(ctx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// Session level lock belongs to the connection, so the connection is
+	// kept while the lock is held.
+	conn := d.db.Conn()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock(?)", d.key); err != nil {
+		_ = conn.Close()
+
+		return fmt.Errorf("postgres: %w", err)
+	}
+
+	d.conn = conn
+
+	return nil
+}
+
+// Unlock releases the advisory lock and returns the dedicated connection to
+// the pool.
+func (d *PostgresDriver) Unlock() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.conn == nil {
+		return nil
+	}
+
+	defer func() {
+		// Closed session releases its advisory locks, so the close error
+		// can be ignored.
+		_ = d.conn.Close()
+		d.conn = nil
+	}()
+
+	if _, err := d.conn.Exec("SELECT pg_advisory_unlock(?)", d.key); err != nil {
+		return fmt.Errorf("postgres: %w", err)
+	}
+
+	return nil
+}
+
+// Init creates migrations table if it does not exist.
+func (d *PostgresDriver) Init(ctx context.Context) error {
+	_, err := d.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+Table+` (
+		version bigint PRIMARY KEY,
+		name text NOT NULL,
+		applied_at timestamptz NOT NULL DEFAULT now()
+	)`)
+
+	return err
+}
+
+// Applied returns applied migrations sorted by version.
+func (d *PostgresDriver) Applied(ctx context.Context) ([]Record, error) {
+	var records []Record
+
+	if _, err := d.db.QueryContext(ctx, &records, `SELECT version, name, applied_at FROM `+Table+` ORDER BY version`); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// Apply executes up migration and records the version.
+func (d *PostgresDriver) Apply(ctx context.Context, m *Migration) error {
+	return d.db.WithContext(ctx).RunInTransaction(func(tx *pg.Tx) error {
+		if _, err := tx.Exec(m.Up); err != nil {
+			return err
+		}
+
+		_, err := tx.Exec(`INSERT INTO `+Table+` (version, name) VALUES (?, ?)`, m.Version, m.Name)
+
+		return err
+	})
+}
+
+// Revert executes down migration and removes the version record.
+func (d *PostgresDriver) Revert(ctx context.Context, m *Migration) error {
+	return d.db.WithContext(ctx).RunInTransaction(func(tx *pg.Tx) error {
+		if _, err := tx.Exec(m.Down); err != nil {
+			return err
+		}
+
+		_, err := tx.Exec(`DELETE FROM `+Table+` WHERE version = ?`, m.Version)
+
+		return err
+	})
+}
@@ -0,0 +1,71 @@
+package multierror
+
+import "strings"
+
+// FieldError is a validation error of the config field addressed by dotted
+// path, e.g. connections.rabbitmq.consumers.test_income.routing_key.
+type FieldError struct {
+	Path string
+	Err  error
+}
+
+// Field returns error of the field by path.
+func Field(path string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &FieldError{Path: path, Err: err}
+}
+
+// Error implements error interface. Messages which already start with the
+// field name or the path tail, e.g. "routing_key is empty" or "exchange.type
+// is empty", are joined with the rest of the path instead of duplicating it.
+func (e *FieldError) Error() string {
+	msg := e.Err.Error()
+
+	for i := 0; ; {
+		if tail := e.Path[i:]; strings.HasPrefix(msg, tail+" ") {
+			return e.Path[:i] + msg
+		}
+
+		next := strings.IndexByte(e.Path[i:], '.')
+		if next < 0 {
+			break
+		}
+
+		i += next + 1
+	}
+
+	return e.Path + ": " + msg
+}
+
+// Unwrap returns the original error.
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// Prefix prepends path to the paths of field errors. Plain errors become
+// field errors of the path. Multi errors are prefixed element by element.
+// Returns nil if err is nil.
+func Prefix(err error, path string) error {
+	if err == nil {
+		return nil
+	}
+
+	if merr, ok := err.(Error); ok {
+		res := New()
+
+		for _, e := range merr.Errors() {
+			res.Append(Prefix(e, path))
+		}
+
+		return res
+	}
+
+	if ferr, ok := err.(*FieldError); ok {
+		return &FieldError{Path: path + "." + ferr.Path, Err: ferr.Err}
+	}
+
+	return &FieldError{Path: path, Err: err}
+}
@@ -28,10 +28,22 @@ func (merr *multierr) Error() string {
 	return strings.Join(errs, ", ")
 }
 
+// Append adds err to the errors. Nil errors are skipped, errors of other
+// multi errors are added one by one.
 func (merr *multierr) Append(err error) {
-	if err != nil {
-		merr.append(err)
+	if err == nil {
+		return
 	}
+
+	if other, ok := err.(Error); ok {
+		for _, e := range other.Errors() {
+			merr.Append(e)
+		}
+
+		return
+	}
+
+	merr.append(err)
 }
 
 func (merr *multierr) Len() int {
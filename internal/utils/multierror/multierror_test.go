@@ -29,3 +29,55 @@ func TestNew(t *testing.T) {
 		}
 	})
 }
+
+func TestAppend(t *testing.T) {
+	err1 := errors.New("error 1")
+	err2 := errors.New("error 2")
+
+	multierr := New()
+	multierr.Append(nil)
+	multierr.Append(New(err1, err2))
+	multierr.Append(New())
+
+	if multierr.Len() != 2 {
+		t.Fatalf("got len %d, want len %d", multierr.Len(), 2)
+	}
+}
+
+func TestPrefix(t *testing.T) {
+	errEmptyRoutingKey := errors.New("routing_key is empty")
+	errInvalid := errors.New("invalid value")
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"plain error", Prefix(errInvalid, "server"), "server: invalid value"},
+		{"field error", Prefix(Field("routing_key", errEmptyRoutingKey), "consumers.test"), "consumers.test.routing_key is empty"},
+		{"top level field", Field("routing_key", errEmptyRoutingKey), "routing_key is empty"},
+		{"field with other message", Prefix(Field("qos", errInvalid), "server"), "server.qos: invalid value"},
+		{"nested field", Prefix(Field("exchange.type", errors.New("exchange.type is empty")), "server"), "server.exchange.type is empty"},
+		{
+			"multi error",
+			Prefix(New(Field("addr", errInvalid), Prefix(errInvalid, "server")), "connections"),
+			"connections.addr: invalid value, connections.server: invalid value",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.err.Error(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if Prefix(nil, "server") != nil {
+		t.Error("nil expected")
+	}
+
+	if !errors.Is(Prefix(errInvalid, "server"), errInvalid) {
+		t.Error("prefixed error must wrap the original error")
+	}
+}
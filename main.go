@@ -12,6 +12,17 @@ const ServiceName = "goservice"
 // flag `-ldflags "-X main.Version=${VERSION}"`.
 var ServiceVersion = "0.0.0-develop"
 
+// Build metadata displayed by version command. Values are passed during
+// compilation the same way as ServiceVersion, e.g.
+// `-ldflags "-X main.ServiceCommit=${COMMIT} -X main.ServiceBuildDate=${DATE}"`.
+var (
+	ServiceCommit    = ""
+	ServiceBuildDate = ""
+)
+
 func main() {
-	app.New(ServiceName, ServiceVersion).Run()
+	app.New(ServiceName, ServiceVersion,
+		app.SetCommit(ServiceCommit),
+		app.SetBuildDate(ServiceBuildDate),
+	).Run()
 }
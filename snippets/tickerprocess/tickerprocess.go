@@ -10,7 +10,11 @@ import (
 // Repository describes getting and changing data methods.
 type Repository interface{}
 
-// Process performs work in a separate goroutine.
+// Process performs work in a separate goroutine. Process implements
+// daemon.Component and daemon.Drainer interfaces, so it can be registered in
+// the daemon directly, e.g.
+//
+//	d.Register(tickerprocess.NewProcess(cfg, repo, log))
 type Process struct {
 	config *Config
 	logger *logutil.Entry
@@ -34,11 +38,38 @@ func NewProcess(cfg *Config, repo Repository, log *logutil.Entry) *Process {
 	}
 }
 
+// Name returns component name.
+func (p *Process) Name() string {
+	return "tickerprocess"
+}
+
 // Errors returns errors channel.
 func (p *Process) Errors() <-chan error {
 	return p.errors
 }
 
+// Start runs the process as daemon component.
+func (p *Process) Start() error {
+	p.Run()
+
+	return nil
+}
+
+// Drain stops taking new ticks and waits for the current tick to finish. It
+// is called by the daemon on shutdown before Stop.
+func (p *Process) Drain() error {
+	p.Quit()
+
+	return nil
+}
+
+// Stop stops the process if it is not drained yet.
+func (p *Process) Stop() error {
+	p.Quit()
+
+	return nil
+}
+
 // Run starts goroutine process.
 func (p *Process) Run() {
 	if p.config.Disabled {
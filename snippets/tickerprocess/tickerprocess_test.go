@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/outdead/goservice/internal/app/daemon"
 	"github.com/outdead/goservice/internal/utils/logutil"
 	"github.com/outdead/goservice/snippets/tickerprocess"
 )
@@ -78,3 +79,47 @@ Loop:
 		fmt.Println(string(output.Bytes()))
 	}
 }
+
+func TestProcess_Drain(t *testing.T) {
+	output := &bytes.Buffer{}
+
+	cfg := tickerprocess.Config{
+		StartInterval: 10 * time.Millisecond,
+	}
+
+	log := logutil.New()
+	log.SetLevel("debug")
+	log.SetOutput(output)
+
+	var c daemon.Component = tickerprocess.NewProcess(&cfg, NewFakeRepo(), log.NewEntry())
+
+	drainer, ok := c.(daemon.Drainer)
+	if !ok {
+		t.Fatal("process must implement daemon.Drainer")
+	}
+
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := drainer.Drain(); err != nil {
+		t.Fatal(err)
+	}
+
+	// No ticks are taken after drain.
+	logs := output.String()
+
+	time.Sleep(3 * cfg.StartInterval)
+
+	if output.String() != logs {
+		t.Errorf("process must not tick after drain, got logs: %s", strings.TrimPrefix(output.String(), logs))
+	}
+
+	if !strings.Contains(logs, "process stopped") {
+		t.Errorf("logs message was not found: %q", "process stopped")
+	}
+
+	if err := c.Stop(); err != nil {
+		t.Errorf("stop of drained process error: %s", err)
+	}
+}